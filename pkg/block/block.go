@@ -2,9 +2,9 @@ package block
 
 import (
 	"BrunoCoin/pkg/block/tx"
+	"BrunoCoin/pkg/pow"
 	"BrunoCoin/pkg/proto"
 	"BrunoCoin/pkg/utils"
-	"bytes"
 	"encoding/hex"
 	"fmt"
 	"strconv"
@@ -112,30 +112,20 @@ func (b *Block) Serialize() *proto.Block {
 	}
 }
 
-// SatisfiesPOW tests a hash and a difficulty
-// target to see if the hash satisfies the
-// difficulty target (hash < dif target).
+// SatisfiesPOW tests whether the block's
+// header satisfies a difficulty target under
+// a proof of work algorithm.
 // Inputs:
+// p	pow.POW	the proof of work algorithm
 // dt	string	represents the difficulty
 // target as a hex string
 // Returns:
-// bool True if the hash was less than the
-// difficulty target, false otherwise.
-func (b *Block) SatisfiesPOW(dt string) bool {
-	hsh, err := hex.DecodeString(b.Hash())
-	if err != nil {
-		fmt.Printf("ERROR {Block.SatisfiesPOW}: "+
-			"Could not decode block {%v}"+
-			"-> error in Block.Hash().\n", b.Hash())
-		return false
-	}
-	difTrg, err := hex.DecodeString(dt)
-	if err != nil {
-		fmt.Printf("ERROR {Block.SatisfiesPOW}: "+
-			"Could not decode difficulty target {%v}.\n", dt)
-		return false
-	}
-	return bytes.Compare(hsh, difTrg) == -1
+// bool True if the header's proof of work
+// hash was less than the difficulty target
+// (or the algorithm accepts every header),
+// false otherwise.
+func (b *Block) SatisfiesPOW(p pow.POW, dt string) bool {
+	return p.Satisfies(b.HdrByts(), dt)
 }
 
 // Sz (Size) returns the size of the
@@ -195,8 +185,13 @@ func (b *Block) String() string {
 // string	the hash of the block represented
 // as a hex string
 func (b *Block) Hash() string {
-	pureData := []byte(fmt.Sprintf("%v", b.Hdr))
-	return utils.Hash(pureData)
+	return utils.Hash(b.HdrByts())
+}
+
+// HdrByts (HeaderBytes) returns the bytes of the
+// block's header that get hashed.
+func (b *Block) HdrByts() []byte {
+	return []byte(fmt.Sprintf("%v", b.Hdr))
 }
 
 func (b *Block) NameTag() string {
@@ -64,6 +64,35 @@ func (t *Transaction) SumOutputs() uint32 {
 	return r
 }
 
+// Fee returns the fee paid to the miner
+// of the transaction (inputs - outputs).
+// Returns:
+// uint32	the fee, or 0 if the outputs
+// are worth more than the inputs
+func (t *Transaction) Fee() uint32 {
+	in, out := t.SumInputs(), t.SumOutputs()
+	if out > in {
+		return 0
+	}
+	return in - out
+}
+
+// SignalsRBF (SignalsReplaceByFee) returns whether
+// the transaction may be replaced by a conflicting
+// transaction paying a higher fee, which is the case
+// when any of its inputs signals it.
+// Returns:
+// bool	True if the transaction is replaceable,
+// false otherwise
+func (t *Transaction) SignalsRBF() bool {
+	for _, i := range t.Inputs {
+		if i.SignalsRBF() {
+			return true
+		}
+	}
+	return false
+}
+
 // Hash returns the hash of the underlying
 // protobuf transaction.
 // Returns:
@@ -82,6 +111,31 @@ func (t *Transaction) Hash() string {
 	return utils.Hash(pureData)
 }
 
+// SigHsh (SignatureHash) returns what the unlocking
+// script of one of the transaction's inputs signs
+// (see txo.TransactionOutput.MkSig): the hash of the
+// transaction with every unlocking script left out,
+// along with the index of the input. A signature
+// therefore can't be copied onto another transaction
+// spending the same output, such as one paying
+// someone else.
+// Inputs:
+// i int the index of the input
+// Returns:
+// string the hash as a hex string
+func (t *Transaction) SigHsh(i int) string {
+	pureInputs := make([]string, 0)
+	for _, in := range t.Inputs {
+		pureInputs = append(pureInputs, fmt.Sprintf("%v/%v/%v/%v", in.TransactionHash, in.OutputIndex, in.Amount, in.SequenceNumber))
+	}
+	pureOutputs := make([]string, 0)
+	for _, o := range t.Outputs {
+		pureOutputs = append(pureOutputs, o.Hash())
+	}
+	pureData := []byte(fmt.Sprintf("%v/%v/%v/%v/%v", i, t.Version, t.LockTime, strings.Join(pureInputs, "/"), strings.Join(pureOutputs, "/")))
+	return utils.Hash(pureData)
+}
+
 // IsCoinbase returns whether or not the
 // transaction is a coinbase transaction.
 // Returns:
@@ -107,6 +161,7 @@ func Deserialize(ptx *proto.Transaction) *Transaction {
 			OutputIndex:     ptx.Inputs[i].OutputIndex,
 			UnlockingScript: ptx.Inputs[i].UnlockingScript,
 			Amount:          ptx.Inputs[i].Amount,
+			SequenceNumber:  ptx.Inputs[i].SequenceNumber,
 		}
 	}
 	outputs := make([]*txo.TransactionOutput, len(ptx.Outputs))
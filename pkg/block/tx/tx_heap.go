@@ -13,10 +13,10 @@ import (
 
 // HeapNode (TransactionHeapNode) represents
 // a node within a heap or a priority queue.
-// P	uint32	the priority of the transaction.
+// P	uint64	the priority of the transaction.
 // T	*Transaction	pointer to a transaction.
 type HeapNode struct {
-	P    	uint32
+	P    	uint64
 	T 		*Transaction
 }
 
@@ -43,9 +43,9 @@ func NewTxHeap() *Heap {
 // Add adds a transaction with a particular
 // priority to the transaction heap.
 // Inputs:
-// p	uint32 the priority of the new transaction.
+// p	uint64 the priority of the new transaction.
 // t	*Transaction the new transaction.
-func (h *Heap) Add(p uint32, t *Transaction) {
+func (h *Heap) Add(p uint64, t *Transaction) {
 	if t == nil {
 		fmt.Printf("ERROR {TxHeap.Add}: " +
 			"received a nil transaction.\n")
@@ -214,13 +214,13 @@ func (h *Heap) Has(t *Transaction) bool {
 // RemAbv (RemoveAbove) removes all transactions
 // in the heap that are above a certain priority.
 // Inputs:
-// thresh	uint32	the threshold that dictates
+// thresh	uint64	the threshold that dictates
 // the minimum priority needed in order to be
 // removed from the heap
 // Returns:
 // []*Transaction all transactions that were
 // removed.
-func (h *Heap) RemAbv(thresh uint32) []*Transaction {
+func (h *Heap) RemAbv(thresh uint64) []*Transaction {
 	var rem []*Transaction
 	for i := 0; i < h.Len(); i++ {
 		val := (*h)[i]
@@ -16,11 +16,15 @@ import (
 // protobuf transaction input so methods
 // can be called on it and additional fields
 // may be added.
+// SequenceNumber is between 1 and proto.RBFSeq when
+// the input signals that its transaction may be
+// replaced by one paying a higher fee.
 type TransactionInput struct {
 	TransactionHash string
 	OutputIndex     uint32
 	UnlockingScript string
 	Amount          uint32
+	SequenceNumber  uint32
 }
 
 // Serialize serializes a transaction input to a protobuf
@@ -35,6 +39,7 @@ func (txi *TransactionInput) Serialize() *proto.TransactionInput {
 		OutputIndex:     txi.OutputIndex,
 		UnlockingScript: txi.UnlockingScript,
 		Amount:          txi.Amount,
+		SequenceNumber:  txi.SequenceNumber,
 	}
 }
 
@@ -49,11 +54,25 @@ func Deserialize(inp *proto.TransactionInput) *TransactionInput {
 		UnlockingScript: inp.UnlockingScript,
 		OutputIndex:     inp.OutputIndex,
 		Amount:          inp.Amount,
+		SequenceNumber:  inp.SequenceNumber,
 	}
 	return ip
 }
 
 func (txi *TransactionInput) Hash() string {
-	pureData := []byte(fmt.Sprintf("%v/%v/%v/%v", txi.TransactionHash, txi.OutputIndex, txi.UnlockingScript, txi.Amount))
+	pureData := []byte(fmt.Sprintf("%v/%v/%v/%v/%v", txi.TransactionHash, txi.OutputIndex, txi.UnlockingScript, txi.Amount, txi.SequenceNumber))
 	return utils.Hash(pureData)
 }
+
+// SignalsRBF (SignalsReplaceByFee) returns whether
+// the input allows its transaction to be replaced
+// by one paying a higher fee.
+// Returns:
+// bool True if the sequence number is between 1 and
+// proto.RBFSeq, false otherwise. A sequence number
+// of 0, which is what an input gets when none was
+// set, doesn't signal it, so replacing has to be
+// opted into.
+func (txi *TransactionInput) SignalsRBF() bool {
+	return txi.SequenceNumber >= 1 && txi.SequenceNumber <= proto.RBFSeq
+}
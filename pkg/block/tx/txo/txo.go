@@ -4,6 +4,7 @@ import (
 	"BrunoCoin/pkg/id"
 	"BrunoCoin/pkg/proto"
 	"BrunoCoin/pkg/utils"
+	"bytes"
 	"crypto/ecdsa"
 	"encoding/hex"
 	"fmt"
@@ -28,32 +29,89 @@ type TransactionOutput struct {
 	Liminal       bool
 }
 
+// P2PKHPrfx (PayToPublicKeyHashPrefix) starts a
+// pay-to-public-key-hash locking script, which is
+// followed by the hex encoded hash of the public key
+// (see id.PKHsh). Other locking scripts are a hex
+// encoded public key.
+const P2PKHPrfx = "pkh:"
+
+// MkP2PKH (MakePayToPublicKeyHash) makes a locking
+// script paying the hash of a public key, such as
+// one decoded from an address (see id.PrsAddr).
+// Inputs:
+// pkh []byte the hash of the public key
+// Returns:
+// string the locking script
+func MkP2PKH(pkh []byte) string {
+	return P2PKHPrfx + hex.EncodeToString(pkh)
+}
+
+// PrsP2PKH (ParsePayToPublicKeyHash) returns the hash
+// of the public key that a locking script pays.
+// Inputs:
+// s string the locking script
+// Returns:
+// []byte the hash of the public key
+// bool True if s is a valid pay-to-public-key-hash
+// locking script
+func PrsP2PKH(s string) ([]byte, bool) {
+	if !strings.HasPrefix(s, P2PKHPrfx) {
+		return nil, false
+	}
+	pkh, err := hex.DecodeString(s[len(P2PKHPrfx):])
+	if err != nil || len(pkh) != id.PKHshLen {
+		return nil, false
+	}
+	return pkh, true
+}
+
 // IsUnlckd (IsUnlocked) tests whether an unlocking
 // script successfully unlocks a locking script
 // on the transaction output. It does this by using
 // a public key, a message, and a signature to verify
-// whether the signature is valid.
+// whether the signature is valid. The message is
+// the output along with the transaction spending it
+// (see sigMsg). A
+// pay-to-public-key-hash output is unlocked by the
+// signature followed by a space and the hex encoded
+// public key, which has to hash to the locking
+// script's hash.
 // Inputs:
 // sig	string	signature a.k.a. unlocking script
 // represented as a hex string.
+// sigHsh	string	the signature hash of the input
+// spending the output (see tx.Transaction.SigHsh).
 // Returns:
 // bool	true if the unlocking script actually
 // unlocks the locking script. False otherwise.
-func (o *TransactionOutput) IsUnlckd(sig string) bool {
-	pkb, err := hex.DecodeString(o.LockingScript)
+func (o *TransactionOutput) IsUnlckd(sig string, sigHsh string) bool {
+	pkS := o.LockingScript
+	pkh, isPKH := PrsP2PKH(o.LockingScript)
+	if isPKH {
+		d := strings.Split(sig, " ")
+		if len(d) != 2 {
+			return false
+		}
+		sig, pkS = d[0], d[1]
+	}
+	pkb, err := hex.DecodeString(pkS)
 	if err != nil {
 		fmt.Printf("ERROR {IsUnlckd}: Locking"+
 			"script on the transaction {%v} couldn't"+
 			"decode properly.\n", o.LockingScript)
 		return false
 	}
+	if isPKH && !bytes.Equal(id.PKHsh(pkb), pkh) {
+		return false
+	}
 	pk, err := utils.Byt2PK(pkb)
 	if err != nil {
 		fmt.Printf("ERROR {IsUnlckd}:" +
 			" utils.Byt2PK errored.\n")
 		return false
 	}
-	h, err := hex.DecodeString(o.Hash())
+	h, err := hex.DecodeString(o.sigMsg(sigHsh))
 	if err != nil {
 		fmt.Printf("ERROR {IsUnlckd}: Could not"+
 			" properly decode the hash {%v} of the"+
@@ -119,33 +177,57 @@ func MkTXOLoc(h string, i uint32) string {
 
 // MkSig (MakeSignature) generates
 // an unlocking script (a.k.a. signature) for the
-// transaction output based on a private key.
+// transaction output based on a private key,
+// signing the output along with the transaction
+// spending it. For a pay-to-public-key-hash output
+// the public key follows the signature (see
+// IsUnlckd).
 // Inputs:
-// id	id.ID	the id of the person wanting to
+// i	id.ID	the id of the person wanting to
 // unlock the particular transaction output.
+// sigHsh	string	the signature hash of the input
+// spending the output (see tx.Transaction.SigHsh).
 // Returns:
 // string	The signature represented as a hex string.
 // error	Errors if the signature could not be
-// produced or there was a decoding error.
-func (o *TransactionOutput) MkSig(id id.ID) (string, error) {
-	sk := id.GetPrivateKey()
-	hB, err := hex.DecodeString(o.Hash())
+// produced or there was a decoding error, and
+// id.ErrLckd if the private key is locked away.
+func (o *TransactionOutput) MkSig(i id.ID, sigHsh string) (string, error) {
+	if i == nil || i.GetPrivateKey() == nil {
+		return "", id.ErrLckd
+	}
+	sk := i.GetPrivateKey()
+	hB, err := hex.DecodeString(o.sigMsg(sigHsh))
 	if err != nil {
 		fmt.Printf("ERROR {TransactionOutput.MkSig}: "+
 			"The hash of the transaction output {%v} could "+
 			"not decode -> errored in "+
 			"TransactionOutput.Hash().\n", o.Hash())
-		return "", nil
+		return "", err
 	}
 	sig, err := utils.Sign(sk, hB)
 	if err != nil {
 		fmt.Printf("ERROR {TransactionOutput.MkSig}: " +
 			"The signature could not be formed.\n")
-		return "", nil
+		return "", err
+	}
+	if _, ok := PrsP2PKH(o.LockingScript); ok {
+		sig += " " + hex.EncodeToString(i.GetPublicKeyBytes())
 	}
 	return sig, nil
 }
 
+// sigMsg (signatureMessage) returns the message that
+// unlocks the output: the hash of the output along
+// with the signature hash of the input spending it.
+// Inputs:
+// sigHsh string the signature hash of the input
+// Returns:
+// string the message as a hex string
+func (o *TransactionOutput) sigMsg(sigHsh string) string {
+	return utils.Hash([]byte(fmt.Sprintf("%v/%v", o.Hash(), sigHsh)))
+}
+
 // Serialize serializes a transaction output
 // into a protobuf transaction output so it
 // can properly be sent over the network.
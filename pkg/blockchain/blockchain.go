@@ -288,7 +288,8 @@ func (bc *Blockchain) IsInvalidInput(txi *txi.TransactionInput) bool {
 // the block with the inputted txs reference
 // Returns:
 // bool True if each input from the txs reference a valid
-// utxo
+// utxo, either on the chain or made by an earlier
+// transaction in txs
 func (bc *Blockchain) ChkChainsUTXO(txs []*tx.Transaction, prevHash string) bool {
 	var keys []string
 	lastBlock, found := bc.blocks[prevHash]
@@ -296,14 +297,18 @@ func (bc *Blockchain) ChkChainsUTXO(txs []*tx.Transaction, prevHash string) bool
 	if !found {
 		lastBlock = bc.LastBlock
 	}
+	made := make(map[string]bool)
 	for _, t := range txs {
 		for _, txii := range t.Inputs {
 			key := txo.MkTXOLoc(txii.TransactionHash, txii.OutputIndex)
-			if _, found := lastBlock.utxo[key]; !found {
+			if _, found := lastBlock.utxo[key]; !found && !made[key] {
 				return false
 			}
 			keys = append(keys, key)
 		}
+		for i := range t.Outputs {
+			made[txo.MkTXOLoc(t.Hash(), uint32(i))] = true
+		}
 	}
 	return true
 }
@@ -330,8 +335,8 @@ type UTXOInfo struct {
 // for a certain person.
 // Inputs:
 // amt uint32 the amount of money needed
-// pubKey string the person that the utxo belongs
-// to
+// pubKeys ...string the keys (of one person) that
+// the utxo can belong to
 // Returns:
 // []*UTXOInfo the list of utxo information that
 // is needed to construct transaction inputs for
@@ -350,7 +355,7 @@ type UTXOInfo struct {
 // txo.PrsTXOLoc(...)
 // bc.Lock()
 // bc.Unlock()
-func (bc *Blockchain) GetUTXOForAmt(amt uint32, pubKey string) ([]*UTXOInfo, uint32, bool) {
+func (bc *Blockchain) GetUTXOForAmt(amt uint32, pubKeys ...string) ([]*UTXOInfo, uint32, bool) {
 	bc.Lock()
 	defer bc.Unlock()
 
@@ -365,10 +370,14 @@ func (bc *Blockchain) GetUTXOForAmt(amt uint32, pubKey string) ([]*UTXOInfo, uin
 	if amt == 0 {
 		return utxoForTransaction, 0, true
 	}
+	owned := make(map[string]bool, len(pubKeys))
+	for _, pk := range pubKeys {
+		owned[pk] = true
+	}
 
 	for key, output := range lastUTXO {
 		// this is payable to the pubkey
-		if output.LockingScript == pubKey {
+		if owned[output.LockingScript] {
 			fmt.Printf("utxo found with amt %d\n", output.Amount)
 			txHash, txIndex := txo.PrsTXOLoc(key)
 			newInfo := &UTXOInfo{
@@ -418,15 +427,22 @@ func GenesisBlock(conf *Config) *block.Block {
 // GetBalance gets the balance for a particular person
 // on the network.
 // Inputs:
-// pk string the public key of the person whose balance
-// is trying to be identified represented as a serialized
+// scrpts ...string the locking scripts paying the
+// person whose balance is trying to be identified,
+// such as their public key represented as a serialized
 // hex string
 // Returns:
 // uint32 the balance that the person has
-func (bc *Blockchain) GetBalance(pk string) uint32 {
+func (bc *Blockchain) GetBalance(scrpts ...string) uint32 {
+	bc.Lock()
+	defer bc.Unlock()
+	owned := make(map[string]bool, len(scrpts))
+	for _, s := range scrpts {
+		owned[s] = true
+	}
 	var bal uint32 = 0
 	for _, v := range bc.LastBlock.utxo {
-		if v.LockingScript == pk {
+		if owned[v.LockingScript] {
 			bal += v.Amount
 		}
 	}
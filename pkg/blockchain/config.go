@@ -1,5 +1,7 @@
 package blockchain
 
+import "BrunoCoin/pkg/pow"
+
 /*
  *  Brown University, CS1951L, Summer 2021
  *  Designed by: Colby Anderson, John Roy
@@ -23,10 +25,14 @@ var GENPVK = "307702010104202456b0e8bed5c27dcadb044df1af8eaf714084b61a23d17359fb
 // to GenPK in the genesis transaction.
 // GenPK is the public key for the genesis
 // transaction.
+// POW is the name of the proof of work
+// algorithm blocks have to satisfy (see
+// pow.Get).
 type Config struct {
 	HasChn    bool
 	InitSbsdy uint32
 	GenPK     string
+	POW       string
 }
 
 // DefaultConfig returns the default
@@ -37,6 +43,7 @@ func DefaultConfig() *Config {
 		HasChn:    true,
 		InitSbsdy: 100000,
 		GenPK:     GENPK,
+		POW:       pow.SHA256Nm,
 	}
 }
 
@@ -48,5 +55,6 @@ func NilConfig() *Config {
 		HasChn:    false,
 		InitSbsdy: 100000,
 		GenPK:     GENPK,
+		POW:       pow.SHA256Nm,
 	}
 }
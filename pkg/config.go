@@ -2,9 +2,12 @@ package pkg
 
 import (
 	"BrunoCoin/pkg/blockchain"
+	"BrunoCoin/pkg/fee"
 	"BrunoCoin/pkg/id"
 	"BrunoCoin/pkg/miner"
+	"BrunoCoin/pkg/pow"
 	"BrunoCoin/pkg/wallet"
+	"strings"
 	"time"
 )
 
@@ -19,6 +22,7 @@ import (
 // MnrConf is the configuration for the miner,
 // WtConf is the configuration for the wallet,
 // ChainConf is the configuration for the blockchain,
+// FeeConf is the configuration for the fee estimator,
 // Version is the version that the node is (used for
 // software updates),
 // PeerLimit is the maximum amount of peers the node
@@ -27,11 +31,15 @@ import (
 // node is allowed to keep track of.
 // Port is the port that the node should run on,
 // MxBlkSz is the maximum allowed block size,
+// MinRlyRt is the minimum fee rate (fees per 1000
+// bytes) a transaction needs to pay for the node to
+// accept and relay it.
 type Config struct {
 	IdConf     		*id.Config
 	MnrConf    		*miner.Config
 	WtConf     		*wallet.Config
 	ChainConf  		*blockchain.Config
+	FeeConf    		*fee.Config
 
 	CstmID			bool
 	CstmIDObj		id.ID
@@ -43,6 +51,7 @@ type Config struct {
 	VerTimeout    	time.Duration
 
 	MxBlkSz 		uint32
+	MinRlyRt		fee.Rt
 }
 
 
@@ -61,28 +70,48 @@ func DefaultConfig(port int) *Config {
 		MnrConf:      	miner.DefaultConfig(-1),
 		WtConf:       	wallet.DefaultConfig(),
 		ChainConf:    	blockchain.DefaultConfig(),
+		FeeConf:      	fee.DefaultConfig(),
 		Version:      	0,
 		PeerLimit:    	20,
 		AddrLimit:    	1000,
 		Port:         	port,
 		VerTimeout:    	time.Second * 2,
 		MxBlkSz: 		10000000,
+		MinRlyRt: 		10,
 	}
 	return c
 }
 
+// RegtestConfig (RegressionTestConfig) is a configuration
+// with default settings except that the proof of work is
+// trivial and the difficulty is minimal, so blocks can be
+// made instantly with GenerateBlocks, and addresses have
+// their own version.
+// Inputs:
+// port int the port that the node should start
+// on
+func RegtestConfig(port int) *Config {
+	c := DefaultConfig(port)
+	c.ChainConf.POW = pow.TrivialNm
+	c.MnrConf.InitPOWD = strings.Repeat("f", 64)
+	c.WtConf.AddrVer = id.RegtestAddrVer
+	return c
+}
+
 func TestingConfig(port int) *Config {
 	c := &Config{
 		IdConf:       	id.DefaultConfig(),
 		MnrConf:      	miner.DefaultConfig(-1),
 		WtConf:       	wallet.DefaultConfig(),
 		ChainConf:    	blockchain.DefaultConfig(),
+		FeeConf:      	fee.DefaultConfig(),
 		Version:      	0,
 		PeerLimit:    	20,
 		AddrLimit:    	1000,
 		Port:         	port,
 		VerTimeout:    	time.Second * 2,
 		MxBlkSz: 		10000000,
+		MinRlyRt: 		10,
 	}
 	return c
 }
@@ -99,12 +128,14 @@ func NilConfig(port int) *Config {
 		MnrConf:      	miner.NilConfig(-1),
 		WtConf:       	wallet.NilConfig(),
 		ChainConf:    	blockchain.NilConfig(),
+		FeeConf:      	fee.DefaultConfig(),
 		Version:      	0,
 		PeerLimit:    	20,
 		AddrLimit:    	1000,
 		Port:         	port,
 		VerTimeout:    	time.Second * 2,
 		MxBlkSz: 		10000000,
+		MinRlyRt: 		10,
 	}
 }
 
@@ -120,12 +151,14 @@ func NoMnrConfig(port int) *Config {
 		MnrConf:      	miner.NilConfig(-1),
 		WtConf:       	wallet.DefaultConfig(),
 		ChainConf:    	blockchain.DefaultConfig(),
+		FeeConf:      	fee.DefaultConfig(),
 		Version:      	1,
 		PeerLimit:    	20,
 		AddrLimit:    	1000,
 		Port:         	port,
 		VerTimeout:    	time.Second * 2,
 		MxBlkSz: 		10000000,
+		MinRlyRt: 		10,
 	}
 }
 // SmallTxPConfig is a configuration with default
@@ -139,12 +172,14 @@ func SmallTxPConfig(port int) *Config {
 		MnrConf:      	miner.SmallTxPCapConfig(-1),
 		WtConf:       	wallet.DefaultConfig(),
 		ChainConf:    	blockchain.DefaultConfig(),
+		FeeConf:      	fee.DefaultConfig(),
 		Version:      	0,
 		PeerLimit:    	20,
 		AddrLimit:    	1000,
 		Port:         	port,
 		VerTimeout:    	time.Second * 2,
 		MxBlkSz: 		10000000,
+		MinRlyRt: 		10,
 	}
 	return c
 }
\ No newline at end of file
@@ -0,0 +1,45 @@
+package fee
+
+// Config represents the settings for the
+// fee estimator.
+// MxTrgt (MaxTarget) defines the largest number
+// of blocks that fees can be estimated for.
+// MinRt (MinimumRate) defines the lower bound of
+// the lowest fee rate bucket.
+// MxRt (MaxRate) defines the fee rate above which
+// no more buckets are made.
+// BcktSpcng (BucketSpacing) defines how much larger
+// the lower bound of each bucket is than the last.
+// Dcy (Decay) defines how much the weight of every
+// past data point is multiplied by for each new
+// block, so that old data fades out.
+// Thresh (Threshold) defines the fraction of
+// transactions in a fee rate range that must have
+// been mined within the target for the range to
+// be recommended.
+// MinSmpls (MinimumSamples) defines the (decayed)
+// number of transactions a fee rate range needs
+// before it is considered.
+type Config struct {
+	MxTrgt    int
+	MinRt     Rt
+	MxRt      Rt
+	BcktSpcng float64
+	Dcy       float64
+	Thresh    float64
+	MinSmpls  float64
+}
+
+// DefaultConfig returns the default settings
+// for the fee estimator.
+func DefaultConfig() *Config {
+	return &Config{
+		MxTrgt:    25,
+		MinRt:     10,
+		MxRt:      10000000,
+		BcktSpcng: 1.1,
+		Dcy:       0.99,
+		Thresh:    0.85,
+		MinSmpls:  0.5,
+	}
+}
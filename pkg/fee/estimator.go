@@ -0,0 +1,211 @@
+package fee
+
+import (
+	"BrunoCoin/pkg/block"
+	"BrunoCoin/pkg/block/tx"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Estimator estimates the fee rate a transaction
+// needs to pay in order to be mined within a certain
+// number of blocks. It does this by watching how many
+// blocks transactions took from entering the pool to
+// being mined, grouped into buckets by fee rate.
+// Conf is the configuration for the estimator.
+// bckts are the lower bounds of the fee rate buckets,
+// in increasing order.
+// cnfd (confirmed) is, for each target t and bucket b,
+// the decayed count of transactions in b that were
+// mined within t+1 blocks.
+// ttl (total) is, for each bucket, the decayed count
+// of transactions that were mined or gave up on.
+// rtSum (rateSum) is, for each bucket, the decayed
+// sum of the fee rates of the transactions in ttl.
+// trckd (tracked) are the transactions that have
+// entered the pool but have not been mined yet.
+// hght (height) is the height of the last block seen.
+type Estimator struct {
+	Conf *Config
+
+	bckts []Rt
+	cnfd  [][]float64
+	ttl   []float64
+	rtSum []float64
+
+	trckd map[string]*trckdTx
+	hght  int
+	mutex sync.Mutex
+}
+
+// trckdTx (trackedTransaction) is a transaction that
+// has entered the pool and has not been mined yet.
+// hght (height) is the height of the last block when
+// it entered the pool.
+// bckt (bucket) is the index of its fee rate bucket.
+// rt (rate) is its fee rate.
+type trckdTx struct {
+	hght int
+	bckt int
+	rt   Rt
+}
+
+// New creates a fee estimator with no data.
+// Inputs:
+// c *Config the configuration for the estimator
+// Returns:
+// *Estimator the new estimator
+func New(c *Config) *Estimator {
+	var bckts []Rt
+	for r := float64(c.MinRt); r <= float64(c.MxRt); r *= c.BcktSpcng {
+		if len(bckts) == 0 || Rt(r) > bckts[len(bckts)-1] {
+			bckts = append(bckts, Rt(r))
+		}
+	}
+	cnfd := make([][]float64, c.MxTrgt)
+	for i := range cnfd {
+		cnfd[i] = make([]float64, len(bckts))
+	}
+	return &Estimator{
+		Conf:  c,
+		bckts: bckts,
+		cnfd:  cnfd,
+		ttl:   make([]float64, len(bckts)),
+		rtSum: make([]float64, len(bckts)),
+		trckd: make(map[string]*trckdTx),
+	}
+}
+
+// bcktOf (bucketOf) returns the index of the bucket
+// that a fee rate falls into.
+func (e *Estimator) bcktOf(rt Rt) int {
+	i := sort.Search(len(e.bckts), func(i int) bool { return e.bckts[i] >= rt })
+	if i < len(e.bckts) && e.bckts[i] == rt {
+		return i
+	}
+	if i == 0 {
+		return 0
+	}
+	return i - 1
+}
+
+// Add starts tracking a transaction that just entered
+// the pool.
+// Inputs:
+// t *tx.Transaction the transaction
+// hght int the height of the last block on the main
+// chain when the transaction entered the pool
+func (e *Estimator) Add(t *tx.Transaction, hght int) {
+	if t == nil || t.IsCoinbase() {
+		return
+	}
+	rt := FeeRt(t)
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if _, ok := e.trckd[t.Hash()]; ok {
+		return
+	}
+	e.trckd[t.Hash()] = &trckdTx{hght: hght, bckt: e.bcktOf(rt), rt: rt}
+}
+
+// HndlBlk (HandleBlock) records how many blocks each
+// tracked transaction on a new main chain block took
+// to be mined. Old data is decayed, and transactions
+// that have waited longer than MxTrgt blocks are given
+// up on and counted as never being mined.
+// Inputs:
+// b *block.Block the new block
+// hght int the height of the new block
+func (e *Estimator) HndlBlk(b *block.Block, hght int) {
+	if b == nil {
+		return
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if hght <= e.hght {
+		return
+	}
+	e.hght = hght
+	for i := range e.bckts {
+		for t := range e.cnfd {
+			e.cnfd[t][i] *= e.Conf.Dcy
+		}
+		e.ttl[i] *= e.Conf.Dcy
+		e.rtSum[i] *= e.Conf.Dcy
+	}
+	for _, t := range b.Transactions {
+		tt, ok := e.trckd[t.Hash()]
+		if !ok {
+			continue
+		}
+		delete(e.trckd, t.Hash())
+		blks := hght - tt.hght
+		if blks < 1 {
+			blks = 1
+		}
+		for trgt := blks; trgt <= e.Conf.MxTrgt; trgt++ {
+			e.cnfd[trgt-1][tt.bckt]++
+		}
+		e.ttl[tt.bckt]++
+		e.rtSum[tt.bckt] += float64(tt.rt)
+	}
+	for h, tt := range e.trckd {
+		if hght-tt.hght > e.Conf.MxTrgt {
+			delete(e.trckd, h)
+			e.ttl[tt.bckt]++
+			e.rtSum[tt.bckt] += float64(tt.rt)
+		}
+	}
+}
+
+// EstFeeRt (EstimateFeeRate) returns the lowest fee
+// rate at which transactions have been mined within
+// the inputted number of blocks often enough (Thresh).
+// Fee rate ranges are checked from highest to lowest,
+// merging buckets until there are enough samples, and
+// the search stops at the first range that fails.
+// Transactions still waiting longer than the target
+// count as failures.
+// Inputs:
+// trgt int the number of blocks the transaction
+// should be mined within
+// Returns:
+// Rt the average fee rate of the lowest passing range
+// bool True if there was enough data for an estimate,
+// false otherwise
+func (e *Estimator) EstFeeRt(trgt int) (Rt, bool) {
+	if trgt < 1 {
+		trgt = 1
+	}
+	if trgt > e.Conf.MxTrgt {
+		return 0, false
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	wtng := make([]float64, len(e.bckts))
+	wtngRt := make([]float64, len(e.bckts))
+	for _, tt := range e.trckd {
+		if e.hght-tt.hght > trgt {
+			wtng[tt.bckt]++
+			wtngRt[tt.bckt] += float64(tt.rt)
+		}
+	}
+	var cnfd, ttl, rtSum float64
+	var best Rt
+	fnd := false
+	for i := len(e.bckts) - 1; i >= 0; i-- {
+		cnfd += e.cnfd[trgt-1][i]
+		ttl += e.ttl[i] + wtng[i]
+		rtSum += e.rtSum[i] + wtngRt[i]
+		if ttl < e.Conf.MinSmpls || ttl == 0 {
+			continue
+		}
+		if cnfd/ttl < e.Conf.Thresh {
+			break
+		}
+		best, fnd = Rt(math.Round(rtSum/ttl)), true
+		cnfd, ttl, rtSum = 0, 0, 0
+	}
+	return best, fnd
+}
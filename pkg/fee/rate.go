@@ -0,0 +1,58 @@
+package fee
+
+import (
+	"BrunoCoin/pkg/block/tx"
+	"fmt"
+)
+
+// Rt (Rate) is a fee rate in fees per 1000 bytes,
+// so that rates below one fee per byte can still
+// be told apart and rates compare exactly.
+type Rt uint64
+
+// NewRt (NewRate) returns the rate of paying a fee
+// for a certain number of bytes.
+// Inputs:
+// fee uint64 the fee
+// sz uint64 the size in bytes
+// Returns:
+// Rt the fee rate, rounded down, 0 if sz is 0
+func NewRt(fee uint64, sz uint64) Rt {
+	if sz == 0 {
+		return 0
+	}
+	return Rt(fee * 1000 / sz)
+}
+
+// FeeRt (FeeRate) returns the fee rate of a
+// transaction.
+// Inputs:
+// t *tx.Transaction the transaction
+// Returns:
+// Rt the fee divided by the size
+func FeeRt(t *tx.Transaction) Rt {
+	if t == nil {
+		return 0
+	}
+	return NewRt(uint64(t.Fee()), uint64(t.Sz()))
+}
+
+// Fee returns the fee that a certain number
+// of bytes needs to pay to meet the rate.
+// Inputs:
+// sz uint32 the size in bytes
+// Returns:
+// uint64 the fee, rounded up
+func (r Rt) Fee(sz uint32) uint64 {
+	return (uint64(r)*uint64(sz) + 999) / 1000
+}
+
+// PerB (PerByte) returns the rate in fees
+// per byte.
+func (r Rt) PerB() float64 {
+	return float64(r) / 1000
+}
+
+func (r Rt) String() string {
+	return fmt.Sprintf("%.3f/B", r.PerB())
+}
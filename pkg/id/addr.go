@@ -0,0 +1,175 @@
+package id
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// PKHshLen (PublicKeyHashLength) is the length of
+// the hash of a public key (see PKHsh).
+const PKHshLen = 20
+
+// Address versions, the first byte of an address,
+// which keep addresses of one network from being paid
+// on another.
+// MainAddrVer (MainAddressVersion) is the version of
+// addresses on the main network.
+// RegtestAddrVer (RegressionTestAddressVersion) is
+// the version of addresses in regtest mode.
+const (
+	MainAddrVer    byte = 0x19
+	RegtestAddrVer byte = 0x6f
+)
+
+// ErrInvldAddr (ErrorInvalidAddress) is returned
+// (possibly wrapped) when an address can't be
+// decoded, such as because it was mistyped.
+// ErrWrngNet (ErrorWrongNetwork) is returned when an
+// address is valid but belongs to another network.
+var (
+	ErrInvldAddr = errors.New("invalid address")
+	ErrWrngNet   = errors.New("address is for another network")
+)
+
+// b58Alph (base58Alphabet) is the alphabet of base58,
+// which leaves out 0, O, I and l since they are easily
+// mistaken for one another.
+const b58Alph = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// PKHsh (PublicKeyHash) returns the hash of a public
+// key that addresses are made from. It is the first
+// PKHshLen bytes of its double sha256.
+// Inputs:
+// pk []byte the serialized public key
+// Returns:
+// []byte the hash
+func PKHsh(pk []byte) []byte {
+	return dblHsh(pk)[:PKHshLen]
+}
+
+// EncdAddr (EncodeAddress) encodes the hash of a
+// public key as an address (Base58Check): the
+// version, the hash and the first 4 bytes of the
+// double sha256 of both as a checksum, in base58.
+// Inputs:
+// ver byte the version of the address
+// pkh []byte the hash of the public key
+// Returns:
+// string the address
+func EncdAddr(ver byte, pkh []byte) string {
+	b := append([]byte{ver}, pkh...)
+	return b58Encd(append(b, dblHsh(b)[:4]...))
+}
+
+// PKAddr (PublicKeyAddress) returns the address of a
+// public key.
+// Inputs:
+// ver byte the version of the address
+// pk []byte the serialized public key
+// Returns:
+// string the address
+func PKAddr(ver byte, pk []byte) string {
+	return EncdAddr(ver, PKHsh(pk))
+}
+
+// DecdAddr (DecodeAddress) decodes an address,
+// checking its checksum.
+// Inputs:
+// a string the address
+// Returns:
+// byte the version of the address
+// []byte the hash of the public key
+// error wrapping ErrInvldAddr if the address has a
+// character outside of base58, the wrong length or
+// a checksum that doesn't match
+func DecdAddr(a string) (byte, []byte, error) {
+	b, err := b58Decd(a)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(b) != 1+PKHshLen+4 {
+		return 0, nil, fmt.Errorf("address has %v bytes instead of %v: %w", len(b), 1+PKHshLen+4, ErrInvldAddr)
+	}
+	pl, chk := b[:1+PKHshLen], b[1+PKHshLen:]
+	if !bytes.Equal(dblHsh(pl)[:4], chk) {
+		return 0, nil, fmt.Errorf("checksum does not match: %w", ErrInvldAddr)
+	}
+	return pl[0], pl[1:], nil
+}
+
+// PrsAddr (ParseAddress) decodes an address of a
+// network.
+// Inputs:
+// a string the address
+// ver byte the version of the network's addresses
+// Returns:
+// []byte the hash of the public key
+// error wrapping ErrInvldAddr if the address can't
+// be decoded (see DecdAddr), or ErrWrngNet if it has
+// another version
+func PrsAddr(a string, ver byte) ([]byte, error) {
+	v, pkh, err := DecdAddr(a)
+	if err != nil {
+		return nil, err
+	}
+	if v != ver {
+		return nil, fmt.Errorf("address has version %v instead of %v: %w", v, ver, ErrWrngNet)
+	}
+	return pkh, nil
+}
+
+// dblHsh (doubleHash) returns the sha256 of the
+// sha256 of some bytes.
+func dblHsh(b []byte) []byte {
+	h := sha256.Sum256(b)
+	h = sha256.Sum256(h[:])
+	return h[:]
+}
+
+// b58Encd (base58Encode) encodes bytes in base58.
+// Each leading zero byte is encoded as a leading 1.
+func b58Encd(b []byte) string {
+	n := new(big.Int).SetBytes(b)
+	rdx, mod := big.NewInt(58), new(big.Int)
+	var s []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, rdx, mod)
+		s = append(s, b58Alph[mod.Int64()])
+	}
+	for i := 0; i < len(b) && b[i] == 0; i++ {
+		s = append(s, b58Alph[0])
+	}
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+	return string(s)
+}
+
+// b58Decd (base58Decode) decodes a base58 string.
+// Returns:
+// []byte the decoded bytes
+// error wrapping ErrInvldAddr if the string is empty
+// or has a character outside of base58
+func b58Decd(s string) ([]byte, error) {
+	if s == "" {
+		return nil, fmt.Errorf("address is empty: %w", ErrInvldAddr)
+	}
+	n, rdx := new(big.Int), big.NewInt(58)
+	for i, c := range s {
+		d := strings.IndexRune(b58Alph, c)
+		if d < 0 {
+			return nil, fmt.Errorf("character %q at %v is not in base58: %w", c, i, ErrInvldAddr)
+		}
+		n.Mul(n, rdx)
+		n.Add(n, big.NewInt(int64(d)))
+	}
+	zs := 0
+	for zs < len(s) && s[zs] == b58Alph[0] {
+		zs++
+	}
+	return append(make([]byte, zs), n.Bytes()...), nil
+}
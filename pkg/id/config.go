@@ -1,6 +1,24 @@
 package id
 
-type Config struct{}
+// Config represents the configuration (settings)
+// for the id.
+// KsFile (KeystoreFile) is the file the id's key is
+// stored in, encrypted (see Keystore). If it is
+// empty, a new key is made that only lives in
+// memory. If the file doesn't exist yet, it is
+// made with a new key. The wallet's seed is
+// stored in the same file.
+// KsPass (KeystorePassphrase) is the passphrase the
+// keystore is encrypted with and unlocked with when
+// the node starts.
+// LightKDF defines whether a new keystore uses the
+// light scrypt parameters (LightKDF) instead of
+// the standard ones (StdKDF).
+type Config struct {
+	KsFile   string
+	KsPass   string
+	LightKDF bool
+}
 
 func DefaultConfig() *Config {
 	c := &Config{}
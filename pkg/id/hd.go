@@ -0,0 +1,151 @@
+package id
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+)
+
+// Hrdnd (Hardened) is the first hardened child
+// index. Hardened children can only be derived
+// from a private key.
+const Hrdnd uint32 = 1 << 31
+
+// ErrInvldKey (ErrorInvalidKey) is returned when a
+// derivation lands outside of the curve's order. This
+// happens with negligible probability, and the index
+// should just be skipped.
+var ErrInvldKey = errors.New("derived key is invalid")
+
+// HDKey (HierarchicalDeterministicKey) is a private
+// key that child keys can be derived from, in the
+// style of BIP32 but on the P-256 curve.
+// Key is the private key.
+// ChnCd (ChainCode) is the extra entropy mixed into
+// the derivation of children.
+// Dpth (Depth) is how many derivations away from the
+// master key the key is.
+// Idx (Index) is the index the key was derived at.
+type HDKey struct {
+	Key   *ecdsa.PrivateKey
+	ChnCd []byte
+	Dpth  uint8
+	Idx   uint32
+}
+
+// NewSeed makes a random seed for a master key.
+// Returns:
+// []byte the 32 byte seed
+// error if there was not enough randomness
+func NewSeed() ([]byte, error) {
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	return seed, nil
+}
+
+// NewMstrKey (NewMasterKey) makes the master key
+// that a whole tree of keys is derived from.
+// Inputs:
+// seed []byte between 16 and 64 bytes of entropy
+// Returns:
+// *HDKey the master key
+// error if the seed has the wrong length or the
+// key is invalid
+func NewMstrKey(seed []byte) (*HDKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, errors.New("seed must be between 16 and 64 bytes")
+	}
+	mac := hmac.New(sha512.New, []byte("BrunoCoin seed"))
+	mac.Write(seed)
+	i := mac.Sum(nil)
+	k, err := toSK(new(big.Int).SetBytes(i[:32]))
+	if err != nil {
+		return nil, err
+	}
+	return &HDKey{Key: k, ChnCd: i[32:]}, nil
+}
+
+// Chld (Child) derives the child key at an index.
+// Indices of Hrdnd and above are hardened, so that
+// leaking a child private key and the parent chain
+// code doesn't leak the parent private key.
+// Inputs:
+// i uint32 the index of the child
+// Returns:
+// *HDKey the child key
+// error ErrInvldKey if the index has no valid key
+func (k *HDKey) Chld(i uint32) (*HDKey, error) {
+	mac := hmac.New(sha512.New, k.ChnCd)
+	if i >= Hrdnd {
+		mac.Write([]byte{0})
+		mac.Write(k.Key.D.FillBytes(make([]byte, 32)))
+	} else {
+		mac.Write(elliptic.MarshalCompressed(k.Key.Curve, k.Key.X, k.Key.Y))
+	}
+	idx := make([]byte, 4)
+	binary.BigEndian.PutUint32(idx, i)
+	mac.Write(idx)
+	I := mac.Sum(nil)
+	n := k.Key.Curve.Params().N
+	il := new(big.Int).SetBytes(I[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, ErrInvldKey
+	}
+	sk, err := toSK(il.Mod(il.Add(il, k.Key.D), n))
+	if err != nil {
+		return nil, err
+	}
+	return &HDKey{Key: sk, ChnCd: I[32:], Dpth: k.Dpth + 1, Idx: i}, nil
+}
+
+// Drv (Derive) derives the key at a path of child
+// indices below the key.
+// Inputs:
+// pth ...uint32 the child indices, from the top
+// Returns:
+// *HDKey the derived key
+// error ErrInvldKey if any index on the path has
+// no valid key
+func (k *HDKey) Drv(pth ...uint32) (*HDKey, error) {
+	var err error
+	for _, i := range pth {
+		if k, err = k.Chld(i); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// ID returns the key as an id.
+// Returns:
+// *SimpleID the id holding the key
+// error if the key could not be serialized
+func (k *HDKey) ID() (*SimpleID, error) {
+	return NewSmplID(k.Key)
+}
+
+// toSK (toSecretKey) makes a P-256 private key out
+// of a scalar.
+// Inputs:
+// d *big.Int the scalar
+// Returns:
+// *ecdsa.PrivateKey the private key
+// error ErrInvldKey if d is 0 or not below the
+// curve's order
+func toSK(d *big.Int) (*ecdsa.PrivateKey, error) {
+	c := elliptic.P256()
+	if d.Sign() == 0 || d.Cmp(c.Params().N) >= 0 {
+		return nil, ErrInvldKey
+	}
+	sk := &ecdsa.PrivateKey{D: d}
+	sk.Curve = c
+	sk.X, sk.Y = c.ScalarBaseMult(d.FillBytes(make([]byte, 32)))
+	return sk, nil
+}
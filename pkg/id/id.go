@@ -2,6 +2,7 @@ package id
 
 import (
 	"crypto/ecdsa"
+	"os"
 )
 
 type ID interface {
@@ -15,6 +16,36 @@ type ID interface {
 	PrivateKeyToBytes(key *ecdsa.PrivateKey) ([]byte, error)
 }
 
+// New makes the id described by the config, either
+// a new key in memory or one in a keystore file,
+// which is unlocked with conf.KsPass.
+// Inputs:
+// conf *Config the configuration for the id
+// Returns:
+// ID the id
+// error if the keystore could not be made, read or
+// unlocked
 func New(conf *Config) (ID, error) {
-	return CreateSimpleID()
+	if conf.KsFile == "" {
+		return CreateSimpleID()
+	}
+	if _, err := os.Stat(conf.KsFile); os.IsNotExist(err) {
+		i, err := CreateSimpleID()
+		if err != nil {
+			return nil, err
+		}
+		kdf := StdKDF
+		if conf.LightKDF {
+			kdf = LightKDF
+		}
+		return NewKeystore(conf.KsFile, conf.KsPass, i, kdf)
+	}
+	ks, err := OpenKeystore(conf.KsFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := ks.Unlock(conf.KsPass); err != nil {
+		return nil, err
+	}
+	return ks, nil
 }
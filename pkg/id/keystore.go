@@ -0,0 +1,499 @@
+package id
+
+import (
+	"BrunoCoin/pkg/utils"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// ErrLckd (ErrorLocked) is returned when a private
+// key is needed from a keystore that is locked.
+var ErrLckd = errors.New("keystore is locked")
+
+// ErrWrngPass (ErrorWrongPassphrase) is returned when
+// a keystore can't be decrypted with a passphrase.
+var ErrWrngPass = errors.New("wrong keystore passphrase")
+
+// KDFPrms (KeyDerivationFunctionParameters) are the
+// scrypt parameters used to turn a passphrase into
+// the key that a keystore is encrypted with. Higher
+// values make guessing the passphrase slower.
+type KDFPrms struct {
+	N int `json:"n"`
+	R int `json:"r"`
+	P int `json:"p"`
+}
+
+var (
+	// StdKDF (StandardKDF) uses 32MB of memory
+	StdKDF = KDFPrms{N: 1 << 15, R: 8, P: 1}
+	// LightKDF is for testing and slow machines
+	LightKDF = KDFPrms{N: 1 << 12, R: 8, P: 1}
+)
+
+// KsFile (KeystoreFile) is what a keystore writes to
+// disk. The private key (serialized as in SimpleID)
+// is encrypted with AES-256-GCM under a key derived
+// from the passphrase and Salt with scrypt. The public
+// key is kept in the clear (and authenticated along
+// with the private key), so that the keystore can be
+// used to receive money while locked.
+// SeedNonce and SeedCphrtx (SeedCiphertext) hold the
+// wallet's seed (see SetSeed), encrypted under the
+// same key with a nonce of its own. They are empty
+// until a seed is stored.
+type KsFile struct {
+	Ver        int     `json:"version"`
+	PubK       string  `json:"publicKey"`
+	KDF        KDFPrms `json:"kdf"`
+	Salt       string  `json:"salt"`
+	Nonce      string  `json:"nonce"`
+	Cphrtx     string  `json:"ciphertext"`
+	SeedNonce  string  `json:"seedNonce,omitempty"`
+	SeedCphrtx string  `json:"seedCiphertext,omitempty"`
+}
+
+// ksSeed (keystoreSeed) is what is encrypted in the
+// seed fields of a keystore file.
+// Seed is the hex encoded seed.
+// Mnem (Mnemonic) are the words the seed was made
+// from, empty if there are none.
+type ksSeed struct {
+	Seed string `json:"seed"`
+	Mnem string `json:"mnemonic,omitempty"`
+}
+
+// Keystore is an id whose private key is stored in a
+// file, encrypted with a passphrase. The private key
+// is only held in memory while the keystore is
+// unlocked.
+// Path is the file the keystore is stored in.
+// KDF are the scrypt parameters used when the
+// passphrase is changed.
+// f is the contents of the file.
+// pk is the public key.
+// sk is the private key, nil while locked.
+// aead is the cipher the file is encrypted with,
+// which the seed is stored with, nil while locked.
+type Keystore struct {
+	Path string
+	KDF  KDFPrms
+
+	f     *KsFile
+	pk    *ecdsa.PublicKey
+	sk    *ecdsa.PrivateKey
+	aead  cipher.AEAD
+	mutex sync.Mutex
+}
+
+// NewKeystore stores an id in a new keystore file,
+// encrypted with a passphrase. The keystore is
+// returned unlocked.
+// Inputs:
+// path string the file to write the keystore to
+// pass string the passphrase
+// i *SimpleID the id to store
+// kdf KDFPrms the scrypt parameters to use
+// Returns:
+// *Keystore the keystore
+// error if the file already exists or could not be
+// written
+func NewKeystore(path string, pass string, i *SimpleID, kdf KDFPrms) (*Keystore, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, errors.New("keystore file already exists")
+	}
+	ks := &Keystore{Path: path, KDF: kdf, pk: i.PublicKey, sk: i.PrivateKey}
+	f, aead, err := ks.seal(pass, i.PrivateKeyBytes, i.PublicKeyBytes, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := wrtKs(path, f); err != nil {
+		return nil, err
+	}
+	ks.f, ks.aead = f, aead
+	return ks, nil
+}
+
+// OpenKeystore reads a keystore file. The keystore is
+// returned locked.
+// Inputs:
+// path string the file the keystore is stored in
+// Returns:
+// *Keystore the keystore
+// error if the file could not be read or is invalid
+func OpenKeystore(path string) (*Keystore, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &KsFile{}
+	if err := json.Unmarshal(d, f); err != nil {
+		return nil, err
+	}
+	if f.Ver != 1 {
+		return nil, errors.New("unknown keystore version")
+	}
+	pkB, err := hex.DecodeString(f.PubK)
+	if err != nil {
+		return nil, err
+	}
+	pk, err := utils.Byt2PK(pkB)
+	if err != nil {
+		return nil, err
+	}
+	return &Keystore{Path: path, KDF: f.KDF, f: f, pk: pk}, nil
+}
+
+// Unlock decrypts the private key, so that it can be
+// used to sign, and the seed can be read and stored.
+// Inputs:
+// pass string the passphrase
+// Returns:
+// error ErrWrngPass if the passphrase is wrong
+func (ks *Keystore) Unlock(pass string) error {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	skB, aead, err := ks.open(pass)
+	if err != nil {
+		return err
+	}
+	sk, err := utils.Byt2SK(skB)
+	if err != nil {
+		return err
+	}
+	ks.sk, ks.aead = sk, aead
+	return nil
+}
+
+// Lock forgets the private key until the keystore is
+// unlocked again.
+func (ks *Keystore) Lock() {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	ks.sk, ks.aead = nil, nil
+}
+
+// IsLckd (IsLocked) returns whether the keystore is
+// locked.
+func (ks *Keystore) IsLckd() bool {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	return ks.sk == nil
+}
+
+// ChngPass (ChangePassphrase) encrypts the private
+// key and the seed again under a new passphrase, with
+// a new salt, and rewrites the file. The keystore
+// stays locked or unlocked.
+// Inputs:
+// oldPass string the current passphrase
+// newPass string the new passphrase
+// Returns:
+// error ErrWrngPass if oldPass is wrong, or an error
+// writing the file
+func (ks *Keystore) ChngPass(oldPass string, newPass string) error {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	skB, aead, err := ks.open(oldPass)
+	if err != nil {
+		return err
+	}
+	sd, err := ks.openSeed(aead)
+	if err != nil {
+		return err
+	}
+	pkB, _ := hex.DecodeString(ks.f.PubK)
+	f, aead, err := ks.seal(newPass, skB, pkB, sd)
+	if err != nil {
+		return err
+	}
+	if err := wrtKs(ks.Path, f); err != nil {
+		return err
+	}
+	ks.f = f
+	if ks.sk != nil {
+		ks.aead = aead
+	}
+	return nil
+}
+
+// SetSeed stores a seed, such as the one a wallet's
+// keys are derived from, in the keystore file,
+// encrypted like the private key. It replaces any
+// seed stored before.
+// Inputs:
+// seed []byte the seed
+// mnem string the words the seed was made from,
+// which may be empty
+// Returns:
+// error ErrLckd if the keystore is locked, or an
+// error writing the file
+func (ks *Keystore) SetSeed(seed []byte, mnem string) error {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	if ks.aead == nil {
+		return ErrLckd
+	}
+	sd, err := json.Marshal(&ksSeed{Seed: hex.EncodeToString(seed), Mnem: mnem})
+	if err != nil {
+		return err
+	}
+	f := *ks.f
+	if err := sealSeed(ks.aead, &f, sd); err != nil {
+		return err
+	}
+	if err := wrtKs(ks.Path, &f); err != nil {
+		return err
+	}
+	ks.f = &f
+	return nil
+}
+
+// Seed returns the seed stored in the keystore file
+// (see SetSeed).
+// Returns:
+// []byte the seed, nil if none was stored
+// string the words the seed was made from
+// error ErrLckd if the keystore is locked, or if the
+// seed could not be decrypted
+func (ks *Keystore) Seed() ([]byte, string, error) {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	if ks.aead == nil {
+		return nil, "", ErrLckd
+	}
+	sd, err := ks.openSeed(ks.aead)
+	if err != nil || sd == nil {
+		return nil, "", err
+	}
+	s := &ksSeed{}
+	if err := json.Unmarshal(sd, s); err != nil {
+		return nil, "", errors.New("keystore file is corrupt")
+	}
+	seed, err := hex.DecodeString(s.Seed)
+	if err != nil {
+		return nil, "", errors.New("keystore file is corrupt")
+	}
+	return seed, s.Mnem, nil
+}
+
+// GetPrivateKey returns the private key, nil while
+// the keystore is locked.
+func (ks *Keystore) GetPrivateKey() *ecdsa.PrivateKey {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	return ks.sk
+}
+
+// GetPrivateKeyBytes returns the serialized private
+// key, nil while the keystore is locked.
+func (ks *Keystore) GetPrivateKeyBytes() []byte {
+	sk := ks.GetPrivateKey()
+	if sk == nil {
+		return nil
+	}
+	skB, _ := ks.PrivateKeyToBytes(sk)
+	return skB
+}
+
+func (ks *Keystore) GetPublicKey() *ecdsa.PublicKey {
+	return ks.pk
+}
+
+func (ks *Keystore) GetPublicKeyBytes() []byte {
+	pkB, _ := ks.PublicKeyToBytes(ks.pk)
+	return pkB
+}
+
+func (ks *Keystore) BytesToPublicKey(bytes []byte) (*ecdsa.PublicKey, error) {
+	return BytesToPublicKey(bytes)
+}
+
+func (ks *Keystore) PublicKeyToBytes(key *ecdsa.PublicKey) ([]byte, error) {
+	return x509.MarshalPKIXPublicKey(key)
+}
+
+func (ks *Keystore) BytesToPrivateKey(bytes []byte) (*ecdsa.PrivateKey, error) {
+	return x509.ParseECPrivateKey(bytes)
+}
+
+func (ks *Keystore) PrivateKeyToBytes(key *ecdsa.PrivateKey) ([]byte, error) {
+	return x509.MarshalECPrivateKey(key)
+}
+
+// seal encrypts a private key, and a seed if there
+// is one, under a passphrase with a new salt and
+// nonces.
+// Inputs:
+// pass string the passphrase
+// skB []byte the serialized private key
+// pkB []byte the serialized public key
+// sd []byte the seed to encrypt (see ksSeed), nil if
+// there is none
+// Returns:
+// *KsFile the keystore file to write
+// cipher.AEAD the cipher the file is encrypted with
+// error if there was not enough randomness
+func (ks *Keystore) seal(pass string, skB []byte, pkB []byte, sd []byte) (*KsFile, cipher.AEAD, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, err
+	}
+	aead, err := ksAEAD(pass, salt, ks.KDF)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	f := &KsFile{
+		Ver:    1,
+		PubK:   hex.EncodeToString(pkB),
+		KDF:    ks.KDF,
+		Salt:   hex.EncodeToString(salt),
+		Nonce:  hex.EncodeToString(nonce),
+		Cphrtx: hex.EncodeToString(aead.Seal(nil, nonce, skB, pkB)),
+	}
+	if sd != nil {
+		if err := sealSeed(aead, f, sd); err != nil {
+			return nil, nil, err
+		}
+	}
+	return f, aead, nil
+}
+
+// open decrypts the private key in the keystore
+// file. Callers must hold ks.mutex.
+// Inputs:
+// pass string the passphrase
+// Returns:
+// []byte the serialized private key
+// cipher.AEAD the cipher the file is encrypted with
+// error ErrWrngPass if the passphrase is wrong (or
+// the file was tampered with)
+func (ks *Keystore) open(pass string) ([]byte, cipher.AEAD, error) {
+	salt, err1 := hex.DecodeString(ks.f.Salt)
+	nonce, err2 := hex.DecodeString(ks.f.Nonce)
+	ct, err3 := hex.DecodeString(ks.f.Cphrtx)
+	pkB, err4 := hex.DecodeString(ks.f.PubK)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return nil, nil, errors.New("keystore file is corrupt")
+	}
+	aead, err := ksAEAD(pass, salt, ks.f.KDF)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, nil, errors.New("keystore file is corrupt")
+	}
+	skB, err := aead.Open(nil, nonce, ct, pkB)
+	if err != nil {
+		return nil, nil, ErrWrngPass
+	}
+	return skB, aead, nil
+}
+
+// openSeed decrypts the seed in the keystore file.
+// Callers must hold ks.mutex.
+// Inputs:
+// aead cipher.AEAD the cipher the file is encrypted
+// with (see open)
+// Returns:
+// []byte the seed (see ksSeed), nil if there is none
+// error if the seed could not be decrypted
+func (ks *Keystore) openSeed(aead cipher.AEAD) ([]byte, error) {
+	if ks.f.SeedCphrtx == "" {
+		return nil, nil
+	}
+	nonce, err1 := hex.DecodeString(ks.f.SeedNonce)
+	ct, err2 := hex.DecodeString(ks.f.SeedCphrtx)
+	if err1 != nil || err2 != nil || len(nonce) != aead.NonceSize() {
+		return nil, errors.New("keystore file is corrupt")
+	}
+	sd, err := aead.Open(nil, nonce, ct, seedAD(ks.f.PubK))
+	if err != nil {
+		return nil, errors.New("keystore seed could not be decrypted")
+	}
+	return sd, nil
+}
+
+// sealSeed encrypts a seed into a keystore file under
+// a new nonce.
+// Inputs:
+// aead cipher.AEAD the cipher the file is encrypted
+// with
+// f *KsFile the keystore file
+// sd []byte the seed (see ksSeed)
+// Returns:
+// error if there was not enough randomness
+func sealSeed(aead cipher.AEAD, f *KsFile, sd []byte) error {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	f.SeedNonce = hex.EncodeToString(nonce)
+	f.SeedCphrtx = hex.EncodeToString(aead.Seal(nil, nonce, sd, seedAD(f.PubK)))
+	return nil
+}
+
+// seedAD (seedAdditionalData) returns the data that
+// a seed is authenticated along with: the public key,
+// and a label so that the seed and the private key
+// can't be swapped.
+// Inputs:
+// pubK string the hex encoded public key
+func seedAD(pubK string) []byte {
+	return []byte("seed:" + pubK)
+}
+
+// ksAEAD (keystoreAEAD) derives the key a keystore is
+// encrypted with from a passphrase.
+// Inputs:
+// pass string the passphrase
+// salt []byte the salt
+// kdf KDFPrms the scrypt parameters
+// Returns:
+// cipher.AEAD AES-256-GCM under the derived key
+// error if the scrypt parameters are invalid
+func ksAEAD(pass string, salt []byte, kdf KDFPrms) (cipher.AEAD, error) {
+	k, err := utils.Scrypt([]byte(pass), salt, kdf.N, kdf.R, kdf.P, 32)
+	if err != nil {
+		return nil, err
+	}
+	blk, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(blk)
+}
+
+// wrtKs (writeKeystore) writes a keystore file.
+// Inputs:
+// path string the file to write to
+// f *KsFile the contents of the file
+// Returns:
+// error any error that happened while writing
+// the file
+func wrtKs(path string, f *KsFile) error {
+	d, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so that a crash
+	// mid-write doesn't lose the key
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, d, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,162 @@
+package id
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// wrdIdxs (wordIndexes) maps each word of the
+// wordlist (see wrdLst) to its index.
+var wrdIdxs = mkWrdIdxs()
+
+// ErrMnemChk (ErrorMnemonicChecksum) is returned
+// when the words of a mnemonic are all valid, but
+// its checksum doesn't match, which usually means
+// that a word was copied wrong or the words were
+// swapped.
+var ErrMnemChk = errors.New("mnemonic checksum does not match")
+
+// NewMnem (NewMnemonic) makes a BIP39 mnemonic, with
+// the English wordlist, out of random entropy. Every 32
+// bits of entropy add 3 words, and a checksum of
+// the entropy is mixed into the last word.
+// Inputs:
+// bits int the bits of entropy, a multiple of 32
+// between 128 and 256
+// Returns:
+// string the words of the mnemonic, separated by
+// spaces
+// error if bits is invalid or there was not enough
+// randomness
+func NewMnem(bits int) (string, error) {
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return "", errors.New("mnemonic entropy must be a multiple of 32 bits between 128 and 256")
+	}
+	ent := make([]byte, bits/8)
+	if _, err := rand.Read(ent); err != nil {
+		return "", err
+	}
+	return EntToMnem(ent)
+}
+
+// EntToMnem (EntropyToMnemonic) turns entropy into
+// the words of a mnemonic. The first len(ent)/4 bits
+// of the SHA256 hash of the entropy are appended as a
+// checksum, and every 11 bits pick a word.
+// Inputs:
+// ent []byte between 16 and 32 bytes of entropy, a
+// multiple of 4
+// Returns:
+// string the words of the mnemonic
+// error if the entropy has an invalid length
+func EntToMnem(ent []byte) (string, error) {
+	if len(ent) < 16 || len(ent) > 32 || len(ent)%4 != 0 {
+		return "", errors.New("mnemonic entropy must be a multiple of 4 bytes between 16 and 32")
+	}
+	h := sha256.Sum256(ent)
+	b := append(append([]byte{}, ent...), h[0])
+	n := (len(ent)*8 + len(ent)/4) / 11
+	wrds := make([]string, n)
+	for i := range wrds {
+		var idx int
+		for j := 0; j < 11; j++ {
+			bit := i*11 + j
+			idx = idx<<1 | int(b[bit/8]>>(7-bit%8)&1)
+		}
+		wrds[i] = wrd(idx)
+	}
+	return strings.Join(wrds, " "), nil
+}
+
+// MnemToEnt (MnemonicToEntropy) turns the words of
+// a mnemonic back into its entropy, checking that
+// every word is valid and that the checksum matches.
+// Inputs:
+// mnem string the words of the mnemonic, separated by
+// white space in any case
+// Returns:
+// []byte the entropy
+// error if a word is invalid, there is the wrong
+// number of words, or ErrMnemChk if the checksum
+// doesn't match
+func MnemToEnt(mnem string) ([]byte, error) {
+	wrds := strings.Fields(strings.ToLower(mnem))
+	if len(wrds) < 12 || len(wrds) > 24 || len(wrds)%3 != 0 {
+		return nil, fmt.Errorf("mnemonic has %v words, must be a multiple of 3 between 12 and 24", len(wrds))
+	}
+	b := make([]byte, (len(wrds)*11+7)/8)
+	for i, w := range wrds {
+		idx, ok := wrdIdx(w)
+		if !ok {
+			return nil, fmt.Errorf("mnemonic word %v (%q) is not valid", i+1, w)
+		}
+		for j := 0; j < 11; j++ {
+			bit := i*11 + j
+			b[bit/8] |= byte(idx>>(10-j)&1) << (7 - bit%8)
+		}
+	}
+	chkBits := len(wrds) / 3
+	ent := b[:len(wrds)*4/3]
+	h := sha256.Sum256(ent)
+	if b[len(ent)]>>(8-chkBits) != h[0]>>(8-chkBits) {
+		return nil, ErrMnemChk
+	}
+	return ent, nil
+}
+
+// MnemToSeed (MnemonicToSeed) turns a mnemonic and an
+// optional passphrase into the seed of a master key
+// (see NewMstrKey), as BIP39 does: 2048 rounds of
+// PBKDF2 with HMAC-SHA512, salted with "mnemonic" and
+// the passphrase, both normalized to NFKD. The same
+// mnemonic with a different passphrase gives a
+// different seed.
+// Inputs:
+// mnem string the words of the mnemonic
+// pass string the passphrase, which may be empty
+// Returns:
+// []byte the 64 byte seed
+// error if the mnemonic is invalid (see MnemToEnt)
+func MnemToSeed(mnem string, pass string) ([]byte, error) {
+	if _, err := MnemToEnt(mnem); err != nil {
+		return nil, err
+	}
+	nrml := strings.Join(strings.Fields(strings.ToLower(mnem)), " ")
+	salt := norm.NFKD.String("mnemonic" + pass)
+	return pbkdf2.Key([]byte(norm.NFKD.String(nrml)), []byte(salt), 2048, 64, sha512.New), nil
+}
+
+// wrd (word) returns the word at an index.
+// Inputs:
+// i int the index, below 2048
+func wrd(i int) string {
+	return wrdLst[i]
+}
+
+// wrdIdx (wordIndex) returns the index of a word.
+// Inputs:
+// w string the word
+// Returns:
+// int the index of the word
+// bool True if the word is valid, false otherwise
+func wrdIdx(w string) (int, bool) {
+	i, ok := wrdIdxs[w]
+	return i, ok
+}
+
+// mkWrdIdxs (makeWordIndexes) makes the map of words
+// to their indexes (see wrdIdxs).
+func mkWrdIdxs() map[string]int {
+	m := make(map[string]int, len(wrdLst))
+	for i, w := range wrdLst {
+		m[w] = i
+	}
+	return m
+}
@@ -7,6 +7,7 @@ import (
 	"crypto/rand"
 	"crypto/x509"
 	"encoding/hex"
+	"errors"
 )
 
 type SimpleID struct {
@@ -16,16 +17,40 @@ type SimpleID struct {
 	PublicKeyBytes  []byte
 }
 
+// LoadInSmplID (LoadInSimpleID) makes an id out of
+// a serialized public and private key.
+// Inputs:
+// pubK string the public key as a hex string
+// privK string the private key as a hex string
+// Returns:
+// *SimpleID the id
+// error if either key can't be decoded or the keys
+// don't match
 func LoadInSmplID(pubK string, privK string) (*SimpleID, error) {
-	pkB, _ := hex.DecodeString(pubK)
-	skB, _ := hex.DecodeString(privK)
-	pk, _ := utils.Byt2PK(pkB)
-	pVk, _ := utils.Byt2SK(skB)
+	pkB, err := hex.DecodeString(pubK)
+	if err != nil {
+		return nil, err
+	}
+	skB, err := hex.DecodeString(privK)
+	if err != nil {
+		return nil, err
+	}
+	pk, err := utils.Byt2PK(pkB)
+	if err != nil {
+		return nil, err
+	}
+	pVk, err := utils.Byt2SK(skB)
+	if err != nil {
+		return nil, err
+	}
+	if !pk.Equal(&pVk.PublicKey) {
+		return nil, errors.New("public key does not match private key")
+	}
 	id := &SimpleID{
-		PrivateKey: pVk,
-		PrivateKeyBytes: pkB,
-		PublicKey:  pk,
-		PublicKeyBytes: pkB,
+		PrivateKey:      pVk,
+		PrivateKeyBytes: skB,
+		PublicKey:       pk,
+		PublicKeyBytes:  pkB,
 	}
 	return id, nil
 }
@@ -36,6 +61,12 @@ func CreateSimpleID() (*SimpleID, error) {
 	if err != nil {
 		return nil, err
 	}
+	return NewSmplID(privKey)
+}
+
+// NewSmplID (NewSimpleID) makes an id out of
+// a private key.
+func NewSmplID(privKey *ecdsa.PrivateKey) (*SimpleID, error) {
 	id := &SimpleID{
 		PrivateKey: privKey,
 		PublicKey:  &privKey.PublicKey,
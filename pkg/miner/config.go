@@ -1,8 +1,10 @@
 package miner
 
 import (
+	"BrunoCoin/pkg/fee"
 	"BrunoCoin/pkg/utils"
 	"math"
+	"time"
 )
 
 /*
@@ -20,9 +22,37 @@ import (
 // time that should be on the coinbase transaction.
 // TxPCap defines the maximum number of
 // transactions allowed in the transaction pool.
-// PriLim defines the priority threshold that
-// must be met for the miner to start mining a
-// group of transactions
+// TxPMxSz (TransactionPoolMaxSize) defines the
+// maximum total size (in bytes) of the transactions
+// stored in the transaction pool.
+// TxPExp (TransactionPoolExpiry) defines how long
+// a transaction may wait in the transaction pool
+// before it is dropped.
+// TxPFile (TransactionPoolFile) defines the file
+// that the transaction pool is saved to when the
+// node is killed and loaded from when it starts.
+// No file is used if TxPFile is empty.
+// MinRtInc (MinimumRateIncrement) defines how
+// far above an evicted transaction's fee rate the
+// pool's minimum fee rate is raised.
+// MinRtHlfLf (MinimumRateHalfLife) defines how
+// long it takes for the pool's minimum fee rate to
+// decay by half.
+// FeeLim (FeeLimit) defines the total fees the
+// transactions in the pool must be expected to pay
+// for the miner to start mining them.
+// MxWt (MaxWait) defines how long a transaction may
+// wait in the pool before the miner mines it even
+// if FeeLim isn't met.
+// MnEmpty (MineEmpty) defines whether the miner
+// mines even when neither is met, including
+// blocks with only a coinbase transaction when
+// the pool is empty.
+// TmpltRfsh (TemplateRefresh) defines how often
+// the miner rebuilds the block it is mining, on
+// top of whenever the pool is updated. The
+// template is never refreshed on a timer if it
+// is 0.
 // BlkSz defines the maximum size a block can be.
 // NncLim defines the maximum nonce that miners
 // are willing to mine to.
@@ -48,7 +78,15 @@ type Config struct {
 	DefLckTm uint32
 
 	TxPCap     uint32
-	PriLim     uint32
+	TxPMxSz    uint32
+	TxPExp     time.Duration
+	TxPFile    string
+	MinRtInc   fee.Rt
+	MinRtHlfLf time.Duration
+	FeeLim     uint32
+	MxWt       time.Duration
+	MnEmpty    bool
+	TmpltRfsh  time.Duration
 
 	BlkSz  uint32
 	NncLim uint32
@@ -67,7 +105,15 @@ func DefaultConfig(powdNumZeros int) *Config {
 		Ver:         0,
 		DefLckTm:    0,
 		TxPCap:      50,
-		PriLim:      10,
+		TxPMxSz:     10000,
+		TxPExp:      time.Hour * 24,
+		TxPFile:     "",
+		MinRtInc:    10,
+		MinRtHlfLf:  time.Hour * 12,
+		FeeLim:      10,
+		MxWt:        time.Minute,
+		MnEmpty:     false,
+		TmpltRfsh:   time.Second * 30,
 		BlkSz:       1000,
 		NncLim:      uint32(math.Pow(2, 20)),
 		InitSubsdy:  10,
@@ -87,7 +133,15 @@ func NilConfig(powdNumZeros int) *Config {
 		Ver:         0,
 		DefLckTm:    0,
 		TxPCap:      50,
-		PriLim:      10,
+		TxPMxSz:     10000,
+		TxPExp:      time.Hour * 24,
+		TxPFile:     "",
+		MinRtInc:    10,
+		MinRtHlfLf:  time.Hour * 12,
+		FeeLim:      10,
+		MxWt:        time.Minute,
+		MnEmpty:     false,
+		TmpltRfsh:   0,
 		BlkSz:       1000,
 		NncLim:      uint32(math.Pow(2, 20)),
 		InitSubsdy:  10,
@@ -106,7 +160,15 @@ func SmallTxPCapConfig(powdNumZeros int) *Config {
 		Ver:         0,
 		DefLckTm:    0,
 		TxPCap:      1,
-		PriLim:      10,
+		TxPMxSz:     10000,
+		TxPExp:      time.Hour * 24,
+		TxPFile:     "",
+		MinRtInc:    10,
+		MinRtHlfLf:  time.Hour * 12,
+		FeeLim:      10,
+		MxWt:        time.Minute,
+		MnEmpty:     false,
+		TmpltRfsh:   time.Second * 30,
 		BlkSz:       1000,
 		NncLim:      uint32(math.Pow(2, 20)),
 		InitSubsdy:  10,
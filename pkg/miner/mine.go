@@ -3,13 +3,11 @@ package miner
 import (
 	"BrunoCoin/pkg/block"
 	"BrunoCoin/pkg/block/tx"
-	"BrunoCoin/pkg/block/tx/txi"
-	"BrunoCoin/pkg/block/tx/txo"
+	"BrunoCoin/pkg/proto"
 	"BrunoCoin/pkg/utils"
 	"context"
-	"encoding/json"
-	"fmt"
-	"math"
+	"encoding/hex"
+	"time"
 )
 
 /*
@@ -17,50 +15,130 @@ import (
  *  Designed by: Colby Anderson, Parker Ljung
  */
 
-// Mine waits to be told to mine a block
-// or to kill it's thread. If it is asked
-// to mine, it selects the transactions
-// with the highest priority to add to the
-// mining pool. The nonce is then attempted
-// to be found unless the miner is stopped.
-func (m *Miner) Mine() {
-	ctx, cancel := context.WithCancel(context.Background())
+// Mine is the miner's run loop. It waits to be
+// alerted of pool updates (or for its context to
+// be cancelled, which ends it). Every alert cancels
+// the block being mined, and if the miner is still
+// active, mining is restarted (see mnTmplt).
+// Mining is also restarted with a new block
+// template every m.Conf.TmpltRfsh, so that
+// the timestamp stays current and new nonces
+// can be tried, and so that transactions
+// that have waited longer than m.Conf.MxWt
+// get mined. Only one block is mined at a time.
+// Inputs:
+// ctx context.Context the context of the run loop
+func (m *Miner) Mine(ctx context.Context) {
+	defer m.wg.Done()
+	var rfsh <-chan time.Time
+	if m.Conf.TmpltRfsh > 0 {
+		tckr := time.NewTicker(m.Conf.TmpltRfsh)
+		defer tckr.Stop()
+		rfsh = tckr.C
+	}
+	cncl := func() {}
+	done := make(chan struct{})
+	close(done)
+	defer func() {
+		cncl()
+		<-done
+	}()
 	for {
-		<-m.PoolUpdated
-		cancel()
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.PoolUpdated:
+		case <-rfsh:
+		}
+		cncl()
+		<-done
 		if !m.Active.Load() {
 			continue
 		}
-		ctx, cancel = context.WithCancel(context.Background())
-		go func(ctx context.Context) {
-			if !m.TxP.PriMet() {
-				return
-			}
-			m.Mining.Store(true)
-			m.MiningPool = m.NewMiningPool()
-			txs := append([]*tx.Transaction{m.GenCBTx(m.MiningPool)}, m.MiningPool...)
-			b := block.New(m.PrvHsh, txs, m.DifTrg())
-			result := m.CalcNonce(ctx, b)
-			m.Mining.Store(false)
-			if result {
-				utils.Debug.Printf("%v mined %v %v", utils.FmtAddr(m.Addr), b.NameTag(), b.Summarize())
-				m.SendBlk <- b
-				m.HndlBlk(b)
-			}
-		}(ctx)
+		var mnCtx context.Context
+		mnCtx, cncl = context.WithCancel(ctx)
+		done = make(chan struct{})
+		go func(mnCtx context.Context, done chan struct{}) {
+			defer close(done)
+			m.mnTmplt(ctx, mnCtx)
+		}(mnCtx, done)
 	}
-	cancel()
+}
+
+// mnTmplt (mineTemplate) checks that the pool is
+// worth mining (see TxPool.ShldMn), selects the
+// transactions with the highest fee rates to add
+// to the mining pool and tries to find the nonce
+// of a block made out of them. If
+// m.Conf.MnEmpty is set, the miner mines even
+// when the pool isn't worth mining, making blocks
+// with only a coinbase transaction if the pool is
+// empty. A block that is found is sent to the
+// node unless the run loop ends first.
+// Inputs:
+// ctx context.Context the context of the run loop
+// mnCtx context.Context the context of this block,
+// cancelled to give up on it
+func (m *Miner) mnTmplt(ctx context.Context, mnCtx context.Context) {
+	if !m.TxP.ShldMn() && !m.Conf.MnEmpty {
+		return
+	}
+	m.Mining.Store(true)
+	strt := time.Now()
+	m.MiningPool = m.NewMiningPool()
+	b := m.NewTmplt(m.MiningPool, hex.EncodeToString(m.Id.GetPublicKeyBytes()))
+	result := m.CalcNonce(mnCtx, b)
+	m.Mining.Store(false)
+	if !result {
+		return
+	}
+	m.Stats.AddFnd(b.Hash(), b.Transactions[0].LockTime, time.Since(strt))
+	utils.Debug.Printf("%v mined %v %v", utils.FmtAddr(m.Addr), b.NameTag(), b.Summarize())
+	select {
+	case m.SendBlk <- b:
+		m.HndlBlk(b)
+	case <-ctx.Done():
+	}
+}
+
+// NewTmplt (NewTemplate) builds a block (whose
+// nonce still has to be found) on top of the
+// main chain out of transactions and a coinbase
+// transaction paying for them. The timestamp is
+// set to the current time.
+// Inputs:
+// txs []*tx.Transaction the transactions (besides
+// the coinbase tx) to put on the block, which may
+// be empty
+// lckScrpt string the locking script (public key as
+// a hex string) the coinbase transaction should pay
+// Returns:
+// *block.Block the block template
+func (m *Miner) NewTmplt(txs []*tx.Transaction, lckScrpt string) *block.Block {
+	b := block.New(m.Hsh(), append([]*tx.Transaction{m.MkCBTx(txs, lckScrpt)}, txs...), m.DifTrg())
+	b.Hdr.Timestamp = uint32(time.Now().Unix())
+	m.Stats.Tmplts.Inc()
+	return b
 }
 
 // Returns boolean to indicate success
+// The hashes attempted and the time spent are added
+// to the miner's stats.
 func (m *Miner) CalcNonce(ctx context.Context, b *block.Block) bool {
+	strt := time.Now()
+	var hshs uint64
+	defer func() {
+		m.Stats.Hshs.Add(hshs)
+		m.Stats.MnngTm.Add(time.Since(strt))
+	}()
 	for i := uint32(0); i < m.Conf.NncLim; i++ {
 		select {
 		case <-ctx.Done():
 			return false
 		default:
 			b.Hdr.Nonce = i
-			if b.SatisfiesPOW(m.DifTrg()) {
+			hshs++
+			if b.SatisfiesPOW(m.POW, m.DifTrg()) {
 				return true
 			}
 		}
@@ -82,76 +160,65 @@ func (m *Miner) DifTrg() string {
 // It does this by adding the fee reward to the minting reward.
 // Inputs:
 // txs	[]*tx.Transaction the transactions (besides the
-// coinbase tx) that the miner is mining to a block
+// coinbase tx) that the miner is mining to a block, which
+// may be empty
 // Returns:
 // the coinbase transaction that pays the miner the reward
 // for mining the block
-// TODO
-// 1. calculate how many fees you can collect
-// 2. calculate the minting reward
-// for the minting reward: it starts as c.InitSubsdy
-// and gets cut in half every c.SubsdyHlvRt until
-// c.MxHlvgs
-// 3. Then a transaction is made with zero inputs
-// and with an output paying the miner fees + mint
-
-// some functions/fields/methods that might be helpful
-// tx.Deserialize(...)
-// proto.NewTx(...)
-// m.Conf.Ver
-// m.Id.GetPublicKeyBytes()
-// proto.NewTxOutpt(...)
-// hex.EncodeToString(...)
-// m.Conf.DefLckTm
-// m.ChnLen.Load()
-// c.SubsdyHlvRt
-// c.MxHlvgs
-// c.InitSubsdy
-// t.SumInputs()
-// t.SumOutputs()
 func (m *Miner) GenCBTx(txs []*tx.Transaction) *tx.Transaction {
-	if txs == nil || len(txs) == 0 {
-		return nil
-	}
-	outp, _ := json.Marshal(txs)
-	fmt.Println(string(outp))
-	outp, _ = json.Marshal(m.Conf)
-	fmt.Println(string(outp))
-	var fees uint32 = 0
-	for _, transaction := range txs {
-		if transaction == nil {
+	return m.MkCBTx(txs, hex.EncodeToString(m.Id.GetPublicKeyBytes()))
+}
+
+// MkCBTx (MakeCoinbaseTransaction) makes a coinbase
+// transaction paying the fees of the transactions and
+// the minting reward to a locking script. The minting
+// reward starts as c.InitSubsdy and gets cut in half
+// every c.SubsdyHlvRt blocks until c.MxHlvgs. The lock
+// time is set to the index of the block, so that the
+// coinbase transactions of different blocks paying the
+// same amount to the same person have different hashes.
+// Inputs:
+// txs	[]*tx.Transaction the transactions (besides the
+// coinbase tx) that are being mined to a block
+// lckScrpt string the locking script (public key as a
+// hex string) to pay
+// Returns:
+// *tx.Transaction the coinbase transaction, nil if any
+// of the transactions are nil
+func (m *Miner) MkCBTx(txs []*tx.Transaction, lckScrpt string) *tx.Transaction {
+	var fees uint32
+	for _, t := range txs {
+		if t == nil {
 			return nil
 		}
-
-		totalInput := transaction.SumInputs()
-		totalOutput := transaction.SumOutputs()
-		transactionFee := uint32(totalInput - totalOutput)
-		fees += transactionFee
+		fees += t.Fee()
 	}
-
-	mintingReward := m.Conf.InitSubsdy
-	chainLength := m.ChnLen.Load()
-	timesToHalf := chainLength / m.Conf.SubsdyHlvRt
-	if timesToHalf > m.Conf.MxHlvgs {
-		timesToHalf = m.Conf.MxHlvgs
+	hlvgs := m.ChnLen.Load() / m.Conf.SubsdyHlvRt
+	if hlvgs > m.Conf.MxHlvgs {
+		hlvgs = m.Conf.MxHlvgs
 	}
+	mint := m.Conf.InitSubsdy >> hlvgs
+	outs := []*proto.TransactionOutput{proto.NewTxOutpt(mint+fees, lckScrpt)}
+	return tx.Deserialize(proto.NewTx(m.Conf.Ver, nil, outs, m.ChnLen.Load()))
+}
 
-	mintingReward = mintingReward / uint32(math.Pow(2, float64(timesToHalf)))
-
-	total := mintingReward + fees
-
-	fmt.Printf("Fees: %d\nMint: %d\nTotal: %d\n", fees, mintingReward, total)
-	newTransactionOutput := &txo.TransactionOutput{
-		Amount:        total,
-		LockingScript: string(m.Id.GetPublicKeyBytes()), // This is just a guess, the documentation doesn't say anything about what the locking script for the coinbase tx should be
-		Liminal:       false,
-	}
-	outp, _ = json.Marshal(newTransactionOutput)
-	fmt.Println(string(outp))
-	newTransaction := &tx.Transaction{
-		Version: m.Conf.Ver,
-		Inputs:  []*txi.TransactionInput{},
-		Outputs: []*txo.TransactionOutput{newTransactionOutput},
+// GenBlk (GenerateBlock) makes a block out of the
+// transaction pool right away, instead of waiting to
+// be told to mine. This is used to generate blocks on
+// demand in regtest mode, where the proof of work is
+// trivial.
+// Inputs:
+// lckScrpt string the locking script (public key as a
+// hex string) the coinbase transaction should pay
+// Returns:
+// *block.Block the mined block, nil if no nonce was
+// found
+func (m *Miner) GenBlk(lckScrpt string) *block.Block {
+	strt := time.Now()
+	b := m.NewTmplt(m.NewMiningPool(), lckScrpt)
+	if !m.CalcNonce(context.Background(), b) {
+		return nil
 	}
-	return newTransaction
+	m.Stats.AddFnd(b.Hash(), b.Transactions[0].LockTime, time.Since(strt))
+	return b
 }
@@ -5,7 +5,10 @@ import (
 	"BrunoCoin/pkg/block/tx"
 	"BrunoCoin/pkg/blockchain"
 	"BrunoCoin/pkg/id"
+	"BrunoCoin/pkg/pow"
 	"BrunoCoin/pkg/utils"
+	"context"
+	"expvar"
 	"sync"
 
 	"go.uber.org/atomic"
@@ -23,10 +26,16 @@ import (
 // MiningPool contains all transactions that the miner is currently mining.
 // PrvHsh represents the hash of the last block on the main chain.
 // ChnLen is the length of the main chain.
-// Active is a channel used to entirely shut down the miner's ability to mine.
+// Active tells whether the miner is running (started and not paused or killed).
 // Mining tells whether the miner is currently mining.
 // SendBlk is used to send newly mined blocks to the node in order to be broadcast on the network.
-// PoolUpdated is used to send alerts of pool updates to the miner
+// PoolUpdated is used to send alerts of pool updates to the miner. It holds at most one alert and is never closed, so
+// alerts are sent with Updt without blocking.
+// POW is the proof of work algorithm the miner's blocks have to satisfy.
+// Stats counts the work the miner has done (see Status for a snapshot).
+// stt is the state of the miner's lifecycle.
+// cncl cancels the context of the miner's run loop.
+// wg waits for the run loop and the mining go routine to finish.
 type Miner struct {
 	Conf *Config
 	Id   id.ID
@@ -44,9 +53,42 @@ type Miner struct {
 	SendBlk     chan *block.Block
 	PoolUpdated chan bool
 
+	POW   pow.POW
+	Stats *Stats
+
+	stt   State
+	cncl  context.CancelFunc
+	wg    sync.WaitGroup
 	mutex sync.Mutex
 }
 
+// State is a state in the miner's lifecycle. A miner
+// starts Idle, is Running once started, can be Paused
+// and resumed any number of times and is Stopped for
+// good once killed.
+type State uint32
+
+const (
+	Idle State = iota
+	Running
+	Paused
+	Stopped
+)
+
+func (s State) String() string {
+	switch s {
+	case Idle:
+		return "idle"
+	case Running:
+		return "running"
+	case Paused:
+		return "paused"
+	case Stopped:
+		return "stopped"
+	}
+	return "unknown"
+}
+
 // New constructs a new Miner according to a config and the id of a node.
 func New(c *Config, id id.ID) *Miner {
 	if !c.HasMnr {
@@ -60,25 +102,55 @@ func New(c *Config, id id.ID) *Miner {
 		PrvHsh:      blockchain.GenesisBlock(blockchain.DefaultConfig()).Hash(),
 		ChnLen:      atomic.NewUint32(1),
 		SendBlk:     make(chan *block.Block),
-		PoolUpdated: make(chan bool),
+		PoolUpdated: make(chan bool, 1),
 		Mining:      atomic.NewBool(false),
 		Active:      atomic.NewBool(false),
+		POW:         pow.SHA256{},
+		Stats:       NewStats(),
 	}
 }
 
-// SetAddr (SetAddress) sets the address of the node that the miner is currently on.
+// SetAddr (SetAddress) sets the address of the node that the miner is currently on, and publishes the miner's status
+// under that address.
 func (m *Miner) SetAddr(a string) {
 	m.mutex.Lock()
 	m.Addr = a
 	m.mutex.Unlock()
+	mtrcs.Set(a, expvar.Func(func() interface{} { return m.Status() }))
+}
+
+// State returns the state of the miner's lifecycle.
+func (m *Miner) State() State {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.stt
 }
 
-// StartMiner is a wrapper around the mine method just in case any additional work is needed to do before or after
-// mining in the future.
-func (m *Miner) StartMiner() {
+// StartMiner starts the miner's run loop (see Mine) if the miner is idle. The loop runs until the miner is killed.
+// Returns:
+// bool True if the miner was started, false if it had already been started
+func (m *Miner) StartMiner() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.stt != Idle {
+		return false
+	}
+	ctx, cncl := context.WithCancel(context.Background())
+	m.stt, m.cncl = Running, cncl
 	m.Active.Store(true)
-	go m.Mine()
-	m.PoolUpdated <- true
+	m.wg.Add(1)
+	go m.Mine(ctx)
+	m.Updt()
+	return true
+}
+
+// Updt (Update) alerts the miner that the pool or the chain has changed, without blocking. If an alert is already
+// waiting to be handled, the new one is merged into it.
+func (m *Miner) Updt() {
+	select {
+	case m.PoolUpdated <- true:
+	default:
+	}
 }
 
 // HndlBlk (HandleBlock) handles a validated block from the network. The transactions on the block need to be checked
@@ -119,15 +191,19 @@ func (m *Miner) HndlBlk(b *block.Block) {
 func (m *Miner) HndlChkBlk(b *block.Block) {
 	m.TxP.ChkTxs(b.Transactions)
 	if m.Active.Load() {
-		m.PoolUpdated <- true
+		m.Updt()
 	}
 }
 
 // HndlTx (HandleTransaction) handles a validated transaction from the network. If the transaction is not an orphan, it
-// is added to the transaction pool. If the miner isn't currently mining and the priority threshold is met, then the
-// miner is told to mine. If the transaction is an orphan, then it is added to the orphan pool.
+// is added to the transaction pool, possibly replacing transactions it conflicts with. If the pool turns the
+// transaction away (see TxPool.Add), nothing else happens. If the miner isn't currently mining and the priority
+// threshold is met, then the miner is told to mine. If the transaction is an orphan, then it is added to the orphan
+// pool.
 // Inputs:
 // t *tx.Transaction the validated transaction that was received from the network
+// Returns:
+// bool True if the transaction was added to the transaction pool, false otherwise
 // TODO
 // 1. Adds the transaction to the transaction pool
 // 2. If active, sends an update to the mining go routine
@@ -137,12 +213,14 @@ func (m *Miner) HndlChkBlk(b *block.Block) {
 // Some functions/methods/fields that might be helpful:
 // m.TxP.Add(...)
 // m.PoolUpdated <- ...
-func (m *Miner) HndlTx(t *tx.Transaction) {
-	m.TxP.Add(t)
+func (m *Miner) HndlTx(t *tx.Transaction) bool {
+	if !m.TxP.Add(t) {
+		return false
+	}
 	if !m.Mining.Load() {
-		m.PoolUpdated <- true
+		m.Updt()
 	}
-	return
+	return true
 }
 
 // SetChnLen (SetChainLength) sets the miner's perspective of the length of the main chain.
@@ -161,26 +239,68 @@ func (m *Miner) SetHash(h string) {
 	m.mutex.Unlock()
 }
 
+// Hsh (Hash) returns the hash of the block the miner is trying to append to.
+func (m *Miner) Hsh() string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.PrvHsh
+}
+
 // IncChnLen (IncrementChainLength) increments the miner's perspective of the length of the main chain.
 func (m *Miner) IncChnLen() {
 	m.ChnLen.Inc()
 }
 
-func (m *Miner) Pause() {
-	m.Active.Store(false)
-	m.PoolUpdated <- true
+// Pause stops the miner from mining until it is resumed. The block being mined is given up on.
+// Returns:
+// bool True if the miner was running, false otherwise
+func (m *Miner) Pause() bool {
+	if !m.setStt(Running, Paused) {
+		return false
+	}
 	utils.Debug.Printf("%v paused mining", utils.FmtAddr(m.Addr))
+	return true
 }
 
-func (m *Miner) Resume() {
-	m.Active.Store(true)
-	m.PoolUpdated <- true
+// Resume lets a paused miner mine again.
+// Returns:
+// bool True if the miner was paused, false otherwise
+func (m *Miner) Resume() bool {
+	if !m.setStt(Paused, Running) {
+		return false
+	}
 	utils.Debug.Printf("%v resumed mining", utils.FmtAddr(m.Addr))
+	return true
 }
 
-// Kill closes all of the miner's channels and stops the current mining process.
+// setStt (setState) moves the miner from one state to another and alerts the run loop.
+// Inputs:
+// frm State the state the miner has to be in
+// to State the state to move to
+// Returns:
+// bool True if the miner was in frm, false otherwise
+func (m *Miner) setStt(frm State, to State) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.stt != frm {
+		return false
+	}
+	m.stt = to
+	m.Active.Store(to == Running)
+	m.Updt()
+	return true
+}
+
+// Kill stops the miner for good. The run loop and the block being mined are cancelled, and Kill waits for them to
+// finish. The miner's channels are left open, so handling transactions and blocks afterwards is still safe.
 func (m *Miner) Kill() {
+	m.mutex.Lock()
+	cncl := m.cncl
+	m.stt = Stopped
 	m.Active.Store(false)
-	close(m.PoolUpdated)
-	close(m.SendBlk)
+	m.mutex.Unlock()
+	if cncl != nil {
+		cncl()
+	}
+	m.wg.Wait()
 }
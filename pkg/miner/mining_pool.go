@@ -1,6 +1,9 @@
 package miner
 
-import "BrunoCoin/pkg/block/tx"
+import (
+	"BrunoCoin/pkg/block/tx"
+	"sort"
+)
 
 /*
  *  Brown University, CS1951L, Summer 2021
@@ -12,20 +15,131 @@ import "BrunoCoin/pkg/block/tx"
 // that the miner is currently mining.
 type MiningPool []*tx.Transaction
 
+// txPkg (transactionPackage) is a transaction along with all
+// of its ancestors in the transaction pool that
+// have not been selected for the block yet. A
+// transaction can only be mined along with (or
+// after) its ancestors, so it is the package's
+// fee rate that counts, not the transaction's.
+type txPkg struct {
+	txs []*tx.Transaction
+	fee uint64
+	sz  uint64
+}
 
-// NewMiningPool selects the highest priority
-// transactions from the transaction pool.
+// NewMiningPool selects the transactions from the
+// transaction pool to mine next. Transactions are
+// selected by the fee rate of their ancestor package
+// (the transaction plus the ancestors it depends on
+// in the pool), so that a child paying a high fee
+// can pull in a parent paying a low fee (child pays
+// for parent). The package with the highest fee rate
+// that still fits into the block is added (ancestors
+// first) until nothing else fits within Conf.BlkSz.
+// Returns:
+// MiningPool the selected transactions in an order
+// where every transaction comes after its ancestors
 func (m *Miner) NewMiningPool() MiningPool {
+	pool := m.TxP.Txs()
+	byHsh := make(map[string]*tx.Transaction, len(pool))
+	for _, t := range pool {
+		byHsh[t.Hash()] = t
+	}
+	ancs := make(map[string]map[string]bool, len(pool))
+	for _, t := range pool {
+		ancstrs(t, byHsh, ancs)
+	}
 	var txs []*tx.Transaction
 	var blkSz uint32 = 100 // assume coinbase
-	var rankings = *m.TxP.TxQ
-	for i := 0; i < len(rankings); i++ {
-		blkSz += rankings[i].T.Sz()
-		if blkSz < m.Conf.BlkSz {
-			txs = append(txs, rankings[i].T)
-		} else {
+	slctd := make(map[string]bool)
+	skppd := make(map[string]bool)
+	for {
+		var best *txPkg
+		var bestHsh string
+		for _, t := range pool {
+			h := t.Hash()
+			if slctd[h] || skppd[h] {
+				continue
+			}
+			p := newPkg(t, byHsh, ancs[h], slctd)
+			if best == nil || p.fee*best.sz > best.fee*p.sz ||
+				(p.fee*best.sz == best.fee*p.sz && h < bestHsh) {
+				best, bestHsh = p, h
+			}
+		}
+		if best == nil {
 			break
 		}
+		if uint64(blkSz)+best.sz > uint64(m.Conf.BlkSz) {
+			skppd[bestHsh] = true
+			continue
+		}
+		// A transaction always has more ancestors than
+		// any of its own ancestors
+		sort.Slice(best.txs, func(i, j int) bool {
+			hi, hj := best.txs[i].Hash(), best.txs[j].Hash()
+			if len(ancs[hi]) != len(ancs[hj]) {
+				return len(ancs[hi]) < len(ancs[hj])
+			}
+			return hi < hj
+		})
+		for _, t := range best.txs {
+			slctd[t.Hash()] = true
+			txs = append(txs, t)
+		}
+		blkSz += uint32(best.sz)
 	}
 	return txs
 }
+
+// ancstrs (ancestors) finds the hashes of every
+// transaction in the pool that a transaction
+// depends on, directly or indirectly, and memoizes
+// them in ancs.
+// Inputs:
+// t *tx.Transaction the transaction
+// byHsh map[string]*tx.Transaction the pool by hash
+// ancs map[string]map[string]bool the memoized
+// ancestors of each transaction
+// Returns:
+// map[string]bool the hashes of t's ancestors
+func ancstrs(t *tx.Transaction, byHsh map[string]*tx.Transaction, ancs map[string]map[string]bool) map[string]bool {
+	h := t.Hash()
+	if a, ok := ancs[h]; ok {
+		return a
+	}
+	a := make(map[string]bool)
+	ancs[h] = a
+	for _, i := range t.Inputs {
+		p, ok := byHsh[i.TransactionHash]
+		if !ok || a[i.TransactionHash] {
+			continue
+		}
+		a[i.TransactionHash] = true
+		for pa := range ancstrs(p, byHsh, ancs) {
+			a[pa] = true
+		}
+	}
+	return a
+}
+
+// newPkg (newPackage) makes the package of a
+// transaction out of it and its ancestors that
+// have not been selected yet.
+func newPkg(t *tx.Transaction, byHsh map[string]*tx.Transaction, ancs map[string]bool, slctd map[string]bool) *txPkg {
+	p := &txPkg{
+		txs: []*tx.Transaction{t},
+		fee: uint64(t.Fee()),
+		sz:  uint64(t.Sz()),
+	}
+	for h := range ancs {
+		if slctd[h] {
+			continue
+		}
+		a := byHsh[h]
+		p.txs = append(p.txs, a)
+		p.fee += uint64(a.Fee())
+		p.sz += uint64(a.Sz())
+	}
+	return p
+}
@@ -0,0 +1,127 @@
+package miner
+
+import (
+	"BrunoCoin/pkg/blockchain"
+	"expvar"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// mtrcs (metrics) publishes the status of every
+// miner in the process, keyed by address, under
+// "miners" on /debug/vars.
+var mtrcs = expvar.NewMap("miners")
+
+// Stats (Statistics) counts the work a miner has done.
+// Hshs is the number of hashes (nonces) attempted.
+// MnngTm is the total time spent attempting nonces.
+// Tmplts is the number of block templates built.
+// BlksFnd is the number of blocks the miner found.
+// BlksStl is the number of found blocks that did not
+// end up on the main chain (stale or orphaned).
+// SlvTm is the total time it took to solve the found
+// blocks, from building the template to finding the nonce.
+// fnd maps the hashes of found blocks that haven't been
+// judged stale or not yet to the index they were mined at.
+type Stats struct {
+	Hshs    *atomic.Uint64
+	MnngTm  *atomic.Duration
+	Tmplts  *atomic.Uint32
+	BlksFnd *atomic.Uint32
+	BlksStl *atomic.Uint32
+	SlvTm   *atomic.Duration
+
+	fnd   map[string]uint32
+	mutex sync.Mutex
+}
+
+// Status is a snapshot of what a miner is doing and
+// has done.
+// HshRt is the hashes per second while mining.
+// AvgSlvTm is the average time it took to solve a block.
+type Status struct {
+	Addr     string
+	Active   bool
+	Mining   bool
+	HshRt    float64
+	Hshs     uint64
+	Tmplts   uint32
+	BlksFnd  uint32
+	BlksStl  uint32
+	AvgSlvTm time.Duration
+}
+
+// NewStats (NewStatistics) returns stats with every
+// counter at zero.
+func NewStats() *Stats {
+	return &Stats{
+		Hshs:    atomic.NewUint64(0),
+		MnngTm:  atomic.NewDuration(0),
+		Tmplts:  atomic.NewUint32(0),
+		BlksFnd: atomic.NewUint32(0),
+		BlksStl: atomic.NewUint32(0),
+		SlvTm:   atomic.NewDuration(0),
+		fnd:     make(map[string]uint32),
+	}
+}
+
+// AddFnd (AddFound) records a block found by the miner.
+// Inputs:
+// h string the hash of the block
+// i uint32 the index the block was mined at
+// d time.Duration how long it took to solve the block
+func (s *Stats) AddFnd(h string, i uint32, d time.Duration) {
+	s.BlksFnd.Inc()
+	s.SlvTm.Add(d)
+	s.mutex.Lock()
+	s.fnd[h] = i
+	s.mutex.Unlock()
+}
+
+// ChkFnd (CheckFound) checks the found blocks against
+// the main chain. Once the main chain reaches the index
+// a block was mined at, the block is stale if it isn't
+// the block at that index.
+// Inputs:
+// bc *blockchain.Blockchain the chain of the node the
+// miner is on
+func (s *Stats) ChkFnd(bc *blockchain.Blockchain) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for h, i := range s.fnd {
+		blks := bc.Slice(int(i), int(i)+1)
+		if len(blks) == 0 {
+			continue
+		}
+		if blks[0].Hash() != h {
+			s.BlksStl.Inc()
+		}
+		delete(s.fnd, h)
+	}
+}
+
+// Status returns a snapshot of the miner's status
+// and stats.
+func (m *Miner) Status() *Status {
+	m.mutex.Lock()
+	a := m.Addr
+	m.mutex.Unlock()
+	s := &Status{
+		Addr:    a,
+		Active:  m.Active.Load(),
+		Mining:  m.Mining.Load(),
+		Hshs:    m.Stats.Hshs.Load(),
+		Tmplts:  m.Stats.Tmplts.Load(),
+		BlksFnd: m.Stats.BlksFnd.Load(),
+		BlksStl: m.Stats.BlksStl.Load(),
+	}
+	if d := m.Stats.MnngTm.Load(); d > 0 {
+		s.HshRt = float64(s.Hshs) / d.Seconds()
+	}
+	if s.BlksFnd > 0 {
+		s.AvgSlvTm = m.Stats.SlvTm.Load() / time.Duration(s.BlksFnd)
+	}
+	return s
+}
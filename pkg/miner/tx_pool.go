@@ -2,7 +2,11 @@ package miner
 
 import (
 	"BrunoCoin/pkg/block/tx"
+	"BrunoCoin/pkg/block/tx/txi"
+	"BrunoCoin/pkg/block/tx/txo"
+	"BrunoCoin/pkg/fee"
 	"sync"
+	"time"
 
 	"go.uber.org/atomic"
 )
@@ -14,23 +18,51 @@ import (
 
 // TxPool represents all the valid transactions
 // that the miner can mine.
-// CurPri is the current cumulative priority of
-// all the transactions.
-// PriLim is the cumulative priority threshold
-// needed to surpass in order to start mining.
+// CurFees is the current total fees of all the
+// transactions.
+// FeeLim is the total fees needed to start mining.
+// MxWt is how long a transaction can wait before
+// mining starts anyway.
 // TxQ is the transaction maximum priority queue
-// that the transactions are stored in.
+// that the transactions are stored in, with their
+// fee rates as priorities.
 // Ct is the current count of the transactions
 // in the pool.
 // Cap is the maximum amount of allowed
 // transactions to store in the pool.
+// CurSz is the current total size (in bytes)
+// of the transactions in the pool.
+// MxSz is the maximum total size (in bytes)
+// of the transactions in the pool.
+// Exp is how long a transaction can stay in
+// the pool before it is dropped.
+// MinRtInc is how far above an evicted
+// transaction's fee rate the minimum fee rate
+// is raised.
+// MinRtHlfLf is the half life of the minimum
+// fee rate.
+// minRt is the minimum fee rate a transaction
+// needs to be let into the pool, as of minRtTm.
+// arrvd maps the hash of every transaction in
+// the pool to when it arrived.
 type TxPool struct {
-	CurPri *atomic.Uint32
-	PriLim uint32
+	CurFees *atomic.Uint64
+	FeeLim  uint32
+	MxWt    time.Duration
 
 	TxQ   *tx.Heap
 	Ct    *atomic.Uint32
 	Cap   uint32
+	CurSz *atomic.Uint32
+	MxSz  uint32
+	Exp   time.Duration
+
+	MinRtInc   fee.Rt
+	MinRtHlfLf time.Duration
+	minRt      fee.Rt
+	minRtTm    time.Time
+
+	arrvd map[string]time.Time
 	mutex sync.Mutex
 }
 
@@ -45,111 +77,487 @@ func (tp *TxPool) Length() uint32 {
 // NewTxPool constructs a transaction pool.
 func NewTxPool(c *Config) *TxPool {
 	return &TxPool{
-		CurPri: atomic.NewUint32(0),
-		PriLim: c.PriLim,
-		TxQ:    tx.NewTxHeap(),
-		Ct:     atomic.NewUint32(0),
-		Cap:    c.TxPCap,
-	}
-}
-
-// PriMet (PriorityMet) checks to see
-// if the transaction pool has enough
-// cumulative priority to start mining.
-func (tp *TxPool) PriMet() bool {
-	return tp.CurPri.Load() >= tp.PriLim
-}
-
-// CalcPri (CalculatePriority) calculates the
-// priority of a transaction by dividing the
-// fees (inputs - outputs) by the size of the
-// transaction and multiplying by a factor of 100.
-// fees * factor / sz
-// TODO
-// 1. Calculate priority using above formula
-// 2. If priority is 0, return 1
-// Tip 1: Remember to do error checking on
-// variables that might be nil
-
-// some functions/fields/methods that might be helpful
-// let t be a transaction object
-// t.Sz()
-func CalcPri(t *tx.Transaction) uint32 {
-	if t == nil {
-		return 0
+		CurFees: atomic.NewUint64(0),
+		FeeLim:  c.FeeLim,
+		MxWt:    c.MxWt,
+		TxQ:     tx.NewTxHeap(),
+		Ct:      atomic.NewUint32(0),
+		Cap:     c.TxPCap,
+		CurSz:   atomic.NewUint32(0),
+		MxSz:    c.TxPMxSz,
+		Exp:     c.TxPExp,
+
+		MinRtInc:   c.MinRtInc,
+		MinRtHlfLf: c.MinRtHlfLf,
+		arrvd:      make(map[string]time.Time),
+	}
+}
+
+// ShldMn (ShouldMine) checks to see if the
+// transactions in the pool are expected to pay
+// enough fees (FeeLim) to start mining, or if any
+// transaction has been waiting for longer than MxWt.
+// Returns:
+// bool True if the miner should mine the pool
+func (tp *TxPool) ShldMn() bool {
+	if tp.Ct.Load() == 0 {
+		return false
+	}
+	if tp.CurFees.Load() >= uint64(tp.FeeLim) {
+		return true
+	}
+	if tp.MxWt <= 0 {
+		return false
+	}
+	tp.mutex.Lock()
+	defer tp.mutex.Unlock()
+	now := time.Now()
+	for _, a := range tp.arrvd {
+		if now.Sub(a) >= tp.MxWt {
+			return true
+		}
+	}
+	return false
+}
+
+// Txs (Transactions) returns every transaction
+// currently in the pool, in no particular order.
+// Returns:
+// []*tx.Transaction the transactions in the pool
+func (tp *TxPool) Txs() []*tx.Transaction {
+	tp.mutex.Lock()
+	defer tp.mutex.Unlock()
+	txs := make([]*tx.Transaction, 0, tp.TxQ.Len())
+	for _, n := range *tp.TxQ {
+		txs = append(txs, n.T)
+	}
+	return txs
+}
+
+// Has returns whether a transaction is in the pool.
+// Inputs:
+// h string the hash of the transaction
+// Returns:
+// bool True if the transaction is in the pool, false
+// otherwise
+func (tp *TxPool) Has(h string) bool {
+	tp.mutex.Lock()
+	defer tp.mutex.Unlock()
+	_, ok := tp.arrvd[h]
+	return ok
+}
+
+// GetUTXO returns the output, made by a transaction
+// in the pool, that a transaction input refers to.
+// This lets transactions spend the outputs of
+// transactions that have not been mined yet.
+// Inputs:
+// i *txi.TransactionInput the transaction input
+// Returns:
+// *txo.TransactionOutput the output, or nil if no
+// transaction in the pool made it
+func (tp *TxPool) GetUTXO(i *txi.TransactionInput) *txo.TransactionOutput {
+	if i == nil {
+		return nil
+	}
+	tp.mutex.Lock()
+	defer tp.mutex.Unlock()
+	for _, n := range *tp.TxQ {
+		if n.T.Hash() == i.TransactionHash && int(i.OutputIndex) < len(n.T.Outputs) {
+			return n.T.Outputs[i.OutputIndex]
+		}
+	}
+	return nil
+}
+
+// MinRt (MinimumRate) returns the fee rate a
+// transaction currently needs to be let into
+// the pool. It is raised whenever a transaction
+// is evicted for lack of space and decays by half
+// every MinRtHlfLf afterwards.
+// Returns:
+// fee.Rt the current minimum fee rate
+func (tp *TxPool) MinRt() fee.Rt {
+	tp.mutex.Lock()
+	defer tp.mutex.Unlock()
+	return tp.curMinRt(time.Now())
+}
+
+// curMinRt (CurrentMinimumRate) decays the
+// minimum fee rate up to the inputted time.
+// Callers must hold tp.mutex.
+func (tp *TxPool) curMinRt(now time.Time) fee.Rt {
+	if tp.minRt == 0 || tp.MinRtHlfLf <= 0 {
+		return tp.minRt
 	}
-	fees := t.SumInputs() - t.SumOutputs()
-	priority := fees * 100 / t.Sz()
-	if priority == 0 {
-		return 1
-	} else {
-		return priority
+	hlvgs := now.Sub(tp.minRtTm) / tp.MinRtHlfLf
+	if hlvgs >= 64 {
+		return 0
 	}
+	return tp.minRt >> uint(hlvgs)
 }
 
 // Add adds a transaction to the transaction pool.
-// If the transaction pool is full, the transaction
-// will not be added. Otherwise, the cumulative
-// priority level is updated, the counter is
-// incremented, and the transaction is added to the
-// heap.
-// TODO
-// 1. Don't add if capacity is reached
-// 2. Add the transaction to the queue with
-// the correct priority
-// Tip 1: Remember this method is mutating state
-// that multiple go routines concurrently have
-// access to
-// Tip 2: Remember to do error checking on
-// variables that might be nil
-
-// Some functions/methods/fields that might be
-// helpful
-// tp.mutex.Lock()
-// tp.mutex.Unlock()
-func (tp *TxPool) Add(t *tx.Transaction) {
+// Transactions that have expired are dropped first.
+// The transaction is rejected if it is already in
+// the pool or its fee rate is below the pool's
+// minimum fee rate. If it spends the same outputs
+// as transactions already in the pool, it replaces
+// them (and any transactions spending their outputs)
+// only if it is allowed to (see rplcs). If the pool
+// is full (by count or by size), the transactions
+// whose packages (the transaction along with any
+// transactions spending its outputs) have the lowest
+// fee rate are evicted to make room, as long as the
+// package has a lower fee rate than the new
+// transaction. Every eviction raises the pool's
+// minimum fee rate. Nothing is removed unless the
+// transaction is added. Otherwise, the total fees
+// are updated, the counter is incremented, and the
+// transaction is added to the heap.
+// Inputs:
+// t *tx.Transaction the transaction to be added
+// Returns:
+// bool True if the transaction was added to the
+// pool, false otherwise
+func (tp *TxPool) Add(t *tx.Transaction) bool {
+	return tp.AddAt(t, time.Now())
+}
+
+// AddAt adds a transaction to the transaction pool
+// the same way as Add, except that the transaction
+// is treated as if it arrived at the inputted time.
+// This is used for transactions that were already
+// in the pool before the node restarted, so that
+// they still expire on time.
+// Inputs:
+// t *tx.Transaction the transaction to be added
+// arrvd time.Time when the transaction first
+// arrived in the pool
+// Returns:
+// bool True if the transaction was added to the
+// pool, false otherwise
+func (tp *TxPool) AddAt(t *tx.Transaction, arrvd time.Time) bool {
 	if t == nil {
-		return
+		return false
 	}
 	tp.mutex.Lock()
 	defer tp.mutex.Unlock()
-	if tp.Ct.Load() < tp.Cap {
-		priority := CalcPri(t)
-		tp.CurPri.Add(priority)
-		tp.Ct.Inc()
-		tp.TxQ.Add(priority, t)
+	now := time.Now()
+	tp.expire(now)
+	if tp.Exp > 0 && now.Sub(arrvd) > tp.Exp {
+		return false
+	}
+	if _, ok := tp.arrvd[t.Hash()]; ok {
+		return false
+	}
+	rt := fee.FeeRt(t)
+	if rt < tp.curMinRt(now) {
+		return false
+	}
+	sz := t.Sz()
+	if sz > tp.MxSz {
+		return false
 	}
-	return
+	var rplcd []*tx.Transaction
+	if cnflcts := tp.cnflcts(t); len(cnflcts) > 0 {
+		if !tp.rplcs(t, cnflcts) {
+			return false
+		}
+		for _, c := range cnflcts {
+			rplcd = append(rplcd, c)
+			rplcd = append(rplcd, tp.dscndnts(c)...)
+		}
+	}
+	evctd, minRt, ok := tp.evctns(t, rplcd)
+	if !ok {
+		return false
+	}
+	tp.rmv(evctd)
+	if minRt > tp.curMinRt(now) {
+		tp.minRt = minRt
+		tp.minRtTm = now
+	}
+	tp.CurFees.Add(uint64(t.Fee()))
+	tp.Ct.Inc()
+	tp.CurSz.Add(sz)
+	tp.TxQ.Add(uint64(rt), t)
+	tp.arrvd[t.Hash()] = arrvd
+	return true
 }
 
-// ChkTxs (CheckTransactions) checks for any duplicate
-// transactions in the heap and removes them.
-// TODO
-// 1. Remove duplicate transactions
-// 2. update count and total priority fields
-// Tip 1: Remember this method is mutating state
-// that multiple go routines concurrently have
-// access to
-// Tip 2: Remember to do error checking on
-// variables that might be nil
-
-// Some functions/methods/fields that might be
-// helpful
-// tp.mutex.Lock()
-// tp.mutex.Unlock()
-// tp.TxQ.Rmv(...)
-func (tp *TxPool) ChkTxs(remover []*tx.Transaction) {
+// Expire drops every transaction that has been
+// waiting in the pool for longer than Exp.
+// Returns:
+// []*tx.Transaction the transactions that were
+// dropped
+func (tp *TxPool) Expire() []*tx.Transaction {
 	tp.mutex.Lock()
 	defer tp.mutex.Unlock()
-	removedTransactions := tp.TxQ.Rmv(remover)
+	return tp.expire(time.Now())
+}
+
+// expire drops every transaction that has been
+// waiting in the pool for longer than Exp as of
+// the inputted time, along with the transactions
+// spending their outputs.
+// Callers must hold tp.mutex.
+func (tp *TxPool) expire(now time.Time) []*tx.Transaction {
+	if tp.Exp <= 0 {
+		return nil
+	}
+	var old []*tx.Transaction
+	for _, n := range *tp.TxQ {
+		if now.Sub(tp.arrvd[n.T.Hash()]) > tp.Exp {
+			old = append(old, n.T)
+		}
+	}
+	if len(old) == 0 {
+		return nil
+	}
+	for _, t := range old {
+		old = append(old, tp.dscndnts(t)...)
+	}
+	return tp.rmv(old)
+}
+
+// evctns (evictions) works out which transactions
+// have to leave the pool for a transaction to be
+// added, without removing any of them: the ones it
+// replaces, then the lowest fee rate packages (see
+// lowestPkg) until it fits. A transaction is scored
+// along with its descendants, since they are evicted
+// with it, so a parent whose child pays for it is
+// scored the way NewMiningPool would mine it. Nothing may be
+// evicted for room unless its package has a lower fee
+// rate than the new transaction, and the new
+// transaction may not spend an output of anything
+// evicted.
+// Callers must hold tp.mutex.
+// Inputs:
+// t *tx.Transaction the transaction to be added
+// rplcd []*tx.Transaction the transactions it
+// replaces, along with their descendants
+// Returns:
+// []*tx.Transaction the transactions to remove
+// fee.Rt what the pool's minimum fee rate is raised
+// to, 0 if nothing is evicted for room
+// bool True if the transaction fits once they are
+// removed, false otherwise
+func (tp *TxPool) evctns(t *tx.Transaction, rplcd []*tx.Transaction) ([]*tx.Transaction, fee.Rt, bool) {
+	var evctd []*tx.Transaction
+	gone := make(map[string]bool)
+	ct, sz := tp.Ct.Load(), tp.CurSz.Load()
+	evct := func(ts []*tx.Transaction) {
+		for _, e := range ts {
+			if !gone[e.Hash()] {
+				gone[e.Hash()] = true
+				evctd = append(evctd, e)
+				ct--
+				sz -= e.Sz()
+			}
+		}
+	}
+	evct(rplcd)
+	rt := fee.FeeRt(t)
+	var minRt fee.Rt
+	for ct >= tp.Cap || sz+t.Sz() > tp.MxSz {
+		low, ds, lowRt := tp.lowestPkg(gone)
+		if low == nil || lowRt >= rt {
+			return nil, 0, false
+		}
+		evct(append([]*tx.Transaction{low}, ds...))
+		if r := lowRt + tp.MinRtInc; r > minRt {
+			minRt = r
+		}
+	}
+	for _, e := range evctd {
+		if spends(t, e) {
+			return nil, 0, false
+		}
+	}
+	return evctd, minRt, true
+}
+
+// lowestPkg (lowestPackage) returns the transaction
+// in the pool whose descendant package (the
+// transaction plus every transaction spending its
+// outputs, directly or not) has the lowest fee rate,
+// leaving out the inputted transactions, or nil if
+// there is none.
+// Callers must hold tp.mutex.
+// Inputs:
+// skp map[string]bool the hashes of the
+// transactions to leave out
+// Returns:
+// *tx.Transaction the transaction
+// []*tx.Transaction its descendants
+// fee.Rt the fee rate of the package
+func (tp *TxPool) lowestPkg(skp map[string]bool) (*tx.Transaction, []*tx.Transaction, fee.Rt) {
+	var low *tx.Transaction
+	var lowDs []*tx.Transaction
+	var lowFee, lowSz uint64
+	for _, n := range *tp.TxQ {
+		if skp[n.T.Hash()] {
+			continue
+		}
+		var ds []*tx.Transaction
+		f, s := uint64(n.T.Fee()), uint64(n.T.Sz())
+		for _, d := range tp.dscndnts(n.T) {
+			if !skp[d.Hash()] {
+				ds = append(ds, d)
+				f += uint64(d.Fee())
+				s += uint64(d.Sz())
+			}
+		}
+		if low == nil || f*lowSz < lowFee*s ||
+			(f*lowSz == lowFee*s && n.T.Hash() < low.Hash()) {
+			low, lowDs, lowFee, lowSz = n.T, ds, f, s
+		}
+	}
+	if low == nil {
+		return nil, nil, 0
+	}
+	return low, lowDs, fee.NewRt(lowFee, lowSz)
+}
+
+// dscndnts (descendants) returns every transaction
+// in the pool that spends an output of the inputted
+// transaction, directly or through other transactions
+// in the pool.
+// Callers must hold tp.mutex.
+func (tp *TxPool) dscndnts(t *tx.Transaction) []*tx.Transaction {
+	var ds []*tx.Transaction
+	seen := map[string]bool{t.Hash(): true}
+	queue := []*tx.Transaction{t}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, n := range *tp.TxQ {
+			if !seen[n.T.Hash()] && spends(n.T, p) {
+				seen[n.T.Hash()] = true
+				ds = append(ds, n.T)
+				queue = append(queue, n.T)
+			}
+		}
+	}
+	return ds
+}
+
+// cnflcts (conflicts) returns every transaction in
+// the pool that spends an output that the inputted
+// transaction also spends.
+// Callers must hold tp.mutex.
+func (tp *TxPool) cnflcts(t *tx.Transaction) []*tx.Transaction {
+	spnt := make(map[string]bool)
+	for _, i := range t.Inputs {
+		spnt[txo.MkTXOLoc(i.TransactionHash, i.OutputIndex)] = true
+	}
+	var cs []*tx.Transaction
+	for _, n := range *tp.TxQ {
+		for _, i := range n.T.Inputs {
+			if spnt[txo.MkTXOLoc(i.TransactionHash, i.OutputIndex)] {
+				cs = append(cs, n.T)
+				break
+			}
+		}
+	}
+	return cs
+}
+
+// rplcs (replaces) checks whether a transaction may
+// replace the transactions in the pool it conflicts
+// with (opt-in replace-by-fee). To be allowed:
+// Every conflicting transaction must signal that it
+// is replaceable.
+// The transaction must pay a strictly higher fee rate
+// than every conflicting transaction.
+// The transaction must pay a strictly higher fee than
+// the conflicting transactions and all of their
+// descendants combined, since they are all evicted.
+// The transaction must not spend an output of any
+// transaction it would evict.
+// Callers must hold tp.mutex.
+func (tp *TxPool) rplcs(t *tx.Transaction, cnflcts []*tx.Transaction) bool {
+	var evctd []*tx.Transaction
+	for _, c := range cnflcts {
+		if !c.SignalsRBF() || fee.FeeRt(t) <= fee.FeeRt(c) {
+			return false
+		}
+		evctd = append(evctd, c)
+		evctd = append(evctd, tp.dscndnts(c)...)
+	}
+	var evctdFee uint64 = 0
+	seen := make(map[string]bool)
+	for _, e := range evctd {
+		if seen[e.Hash()] {
+			continue
+		}
+		seen[e.Hash()] = true
+		if spends(t, e) {
+			return false
+		}
+		evctdFee += uint64(e.Fee())
+	}
+	return uint64(t.Fee()) > evctdFee
+}
+
+// spends returns whether transaction c spends any
+// output of transaction p.
+func spends(c *tx.Transaction, p *tx.Transaction) bool {
+	h := p.Hash()
+	for _, i := range c.Inputs {
+		if i.TransactionHash == h {
+			return true
+		}
+	}
+	return false
+}
+
+// rmv (remove) removes transactions from the heap
+// and updates the count, size and total fees
+// fields.
+// Callers must hold tp.mutex.
+// Returns:
+// []*tx.Transaction the transactions that were
+// actually removed
+func (tp *TxPool) rmv(ts []*tx.Transaction) []*tx.Transaction {
+	removedTransactions := tp.TxQ.Rmv(ts)
 	if removedTransactions == nil {
-		return
+		return nil
 	}
-	var priorityRemoved uint32 = 0
+	var feesRemoved uint64 = 0
+	var szRemoved uint32 = 0
 	for _, removedTx := range removedTransactions {
-		priorityRemoved += CalcPri(removedTx)
+		feesRemoved += uint64(removedTx.Fee())
+		szRemoved += removedTx.Sz()
+		delete(tp.arrvd, removedTx.Hash())
 	}
-	tp.CurPri.Sub(priorityRemoved)
+	tp.CurFees.Sub(feesRemoved)
+	tp.CurSz.Sub(szRemoved)
 	tp.Ct.Sub(uint32(len(removedTransactions)))
-	return
+	return removedTransactions
+}
+
+// ChkTxs (CheckTransactions) checks for any duplicate
+// transactions in the heap and removes them. Any
+// transactions in the heap that conflict with the
+// inputted ones (spend the same outputs) can never
+// be mined anymore, so they are removed as well,
+// along with the transactions spending their outputs.
+// Inputs:
+// remover []*tx.Transaction the transactions that
+// were mined to a block
+func (tp *TxPool) ChkTxs(remover []*tx.Transaction) {
+	tp.mutex.Lock()
+	defer tp.mutex.Unlock()
+	tp.rmv(remover)
+	for _, t := range remover {
+		if t == nil {
+			continue
+		}
+		for _, c := range tp.cnflcts(t) {
+			tp.rmv(append([]*tx.Transaction{c}, tp.dscndnts(c)...))
+		}
+	}
 }
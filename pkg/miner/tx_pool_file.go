@@ -0,0 +1,78 @@
+package miner
+
+import (
+	"BrunoCoin/pkg/proto"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+)
+
+// PoolEntry is a transaction in the transaction
+// pool, as it is stored on disk.
+// Tx is the protobuf transaction.
+// Arrvd (Arrived) is when the transaction first
+// arrived in the pool.
+type PoolEntry struct {
+	Tx    *proto.Transaction
+	Arrvd time.Time
+}
+
+// Dump writes every transaction in the pool to a
+// file, so that they can be loaded back into the
+// pool after the node restarts. The transactions
+// are written in the order they arrived, which puts
+// every transaction after the ones it spends from.
+// Inputs:
+// path string the file to write to
+// Returns:
+// error any error that happened while writing
+// the file
+func (tp *TxPool) Dump(path string) error {
+	tp.mutex.Lock()
+	es := make([]*PoolEntry, 0, tp.TxQ.Len())
+	for _, n := range *tp.TxQ {
+		es = append(es, &PoolEntry{Tx: n.T.Serialize(), Arrvd: tp.arrvd[n.T.Hash()]})
+	}
+	tp.mutex.Unlock()
+	sort.SliceStable(es, func(i, j int) bool {
+		return es[i].Arrvd.Before(es[j].Arrvd)
+	})
+	d, err := json.Marshal(es)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so that a crash
+	// mid-write doesn't leave a corrupt pool file
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, d, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LdPoolFile (LoadPoolFile) reads the transactions
+// that were written to a file by Dump.
+// Inputs:
+// path string the file to read from
+// Returns:
+// []*PoolEntry the transactions in the order they
+// arrived in the pool. Empty if the file does not
+// exist.
+// error any error that happened while reading
+// the file
+func LdPoolFile(path string) ([]*PoolEntry, error) {
+	d, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var es []*PoolEntry
+	if err := json.Unmarshal(d, &es); err != nil {
+		return nil, err
+	}
+	return es, nil
+}
@@ -5,13 +5,18 @@ import (
 	"BrunoCoin/pkg/address/addressdb"
 	"BrunoCoin/pkg/block"
 	"BrunoCoin/pkg/block/tx"
+	"BrunoCoin/pkg/block/tx/txo"
 	"BrunoCoin/pkg/blockchain"
+	"BrunoCoin/pkg/fee"
 	"BrunoCoin/pkg/id"
 	"BrunoCoin/pkg/miner"
 	"BrunoCoin/pkg/peer"
+	"BrunoCoin/pkg/pow"
 	"BrunoCoin/pkg/proto"
 	"BrunoCoin/pkg/utils"
 	"BrunoCoin/pkg/wallet"
+	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"google.golang.org/grpc"
@@ -48,6 +53,9 @@ import (
 // Chain  *blockchain.Blockchain the blockchain
 // Wallet *wallet.Wallet the wallet
 // Mnr    *miner.Miner the miner
+// FeeEst *fee.Estimator the fee estimator
+// POW    pow.POW the proof of work algorithm blocks
+// have to satisfy, picked by the chain's config
 // fGetAddr bool
 // AddrDb   addressdb.AddressDb a database of addresses
 // of nodes that it knows about in the network
@@ -55,11 +63,14 @@ import (
 // is currently connected to
 // TxMap    map[string]bool a map used to keep track
 // of whether a transaction has been seen on the network
-// before or not
+// before or not, guarded by TxMapMutex
 // BlockMap map[string]bool a map used to keep track
 // of whether a block has been seen on the network
 // before or not
 // Paused bool
+// svdTxs (savedTransactions) are transactions loaded
+// from the transaction pool file that have not been
+// restored to the pool yet
 type Node struct {
 	*proto.UnimplementedBrunoCoinServer
 	Server *grpc.Server
@@ -71,16 +82,21 @@ type Node struct {
 	Chain  *blockchain.Blockchain
 	Wallet *wallet.Wallet
 	Mnr    *miner.Miner
+	FeeEst *fee.Estimator
+	POW    pow.POW
 
 	fGetAddr bool // starts false, set to true when we request addresses from a node, cleared when we receive less than 1000 addresses from a node
 
 	AddrDb        addressdb.AddressDb
 	PeerDb        peer.PeerDb
 	TxMap         map[string]bool
+	TxMapMutex    sync.Mutex
 	BlockMap      map[string]bool
 	BlockMapMutex sync.Mutex
 
 	Paused bool
+
+	svdTxs []*miner.PoolEntry
 }
 
 // SendTx (SendTransaction) sends a transaction to
@@ -91,7 +107,8 @@ type Node struct {
 // Inputs:
 // amt uint32 the amount of money to be paid to someone
 // fee uint32 the amount of extra money to be paid to the
-// miner who mines your transaction
+// miner who mines your transaction, 0 to have the wallet
+// estimate it
 // pubK []byte the public key of the person you are sending
 // money to
 func (n *Node) SendTx(amt uint32, fee uint32, pubK []byte) {
@@ -111,6 +128,402 @@ func (n *Node) SendTx(amt uint32, fee uint32, pubK []byte) {
 	go n.Wallet.HndlTxReq(txR)
 }
 
+// SendTxCtx (SendTransactionContext) sends a
+// transaction to someone like SendTx, but waits for
+// the wallet to make it and hand it to the node.
+// Inputs:
+// ctx context.Context cancels waiting for the wallet
+// amt uint32 the amount of money to be paid to someone
+// fee uint32 the fee of the transaction
+// pubK []byte the public key of the person you are sending
+// money to
+// Returns:
+// string the hash of the transaction
+// error if the node has no wallet, ctx was cancelled,
+// or the transaction could not be made, such as
+// wallet.ErrInsfFnds, wallet.ErrInvldRcpnt,
+// wallet.ErrInvldAmt, wallet.ErrFeeTooLow or
+// wallet.ErrLckd (see wallet.Wallet.Snd)
+func (n *Node) SendTxCtx(ctx context.Context, amt uint32, fee uint32, pubK []byte) (string, error) {
+	return n.SendTxReq(ctx, &wallet.TxReq{PubK: pubK, Amt: amt, Fee: fee})
+}
+
+// SendToAddr (SendToAddress) sends a transaction
+// paying someone identified by their address instead
+// of their public key, like SendTxCtx.
+// Inputs:
+// ctx context.Context cancels waiting for the wallet
+// amt uint32 the amount of money to be paid to someone
+// fee uint32 the fee of the transaction
+// addr string the address of the person you are
+// sending money to (see id.PKAddr)
+// Returns:
+// string the hash of the transaction
+// error if the node has no wallet, ctx was cancelled,
+// or the transaction could not be made (see
+// SendTxCtx), wrapping wallet.ErrInvldRcpnt if the
+// address is mistyped or for another network
+func (n *Node) SendToAddr(ctx context.Context, amt uint32, fee uint32, addr string) (string, error) {
+	return n.SendTxReq(ctx, &wallet.TxReq{Addr: addr, Amt: amt, Fee: fee})
+}
+
+// SendTxReq (SendTransactionRequest) sends a
+// transaction made from a request, such as one paying
+// a fee rate instead of a fee, like SendTxCtx.
+// Inputs:
+// ctx context.Context cancels waiting for the wallet
+// txR *wallet.TxReq the transaction request
+// Returns:
+// string the hash of the transaction
+// error if the node has no wallet, ctx was cancelled,
+// or the transaction could not be made (see SendTxCtx)
+func (n *Node) SendTxReq(ctx context.Context, txR *wallet.TxReq) (string, error) {
+	if !n.Conf.WtConf.HasWt {
+		return "", errors.New("node has no wallet")
+	}
+	t, err := n.Wallet.Snd(ctx, txR)
+	if err != nil {
+		return "", err
+	}
+	return t.Hash(), nil
+}
+
+// SendBatch sends one transaction paying many people
+// (identified by their public keys or addresses), with a single
+// change output and a single fee, which is cheaper
+// than a transaction for each of them. It waits until
+// the transaction is made and taken by the node, like
+// SendTxReq.
+// Inputs:
+// ctx context.Context cancels waiting for the node
+// pmts []*wallet.Pmt the people to pay and how much
+// fee uint32 the fee of the transaction
+// Returns:
+// string the hash of the transaction
+// error if the node has no wallet, ctx was cancelled,
+// a payment is invalid (see wallet.ChkPmts) or the
+// transaction could not be made (see SendTxCtx)
+func (n *Node) SendBatch(ctx context.Context, pmts []*wallet.Pmt, fee uint32) (string, error) {
+	return n.SendTxReq(ctx, &wallet.TxReq{Fee: fee, Pmts: pmts})
+}
+
+// BumpFee asks the wallet to replace one of the
+// transactions it made, which has not been mined
+// yet, with one paying a higher fee.
+// Inputs:
+// ctx context.Context cancels waiting for the wallet
+// h string the hash of the transaction to replace
+// fee uint32 the new (higher) fee
+// Returns:
+// *tx.Transaction the replacement transaction
+// error if the node has no wallet or the transaction
+// could not be replaced (see wallet.Wallet.BumpFee)
+func (n *Node) BumpFee(ctx context.Context, h string, fee uint32) (*tx.Transaction, error) {
+	if !n.Conf.WtConf.HasWt {
+		return nil, errors.New("node has no wallet")
+	}
+	return n.Wallet.BumpFee(ctx, h, fee)
+}
+
+// NewRcvPK (NewReceivePublicKey) returns a public
+// key of the node's wallet that hasn't been handed
+// out before, for someone to pay.
+// Returns:
+// []byte the serialized public key
+// error if the node has no wallet
+func (n *Node) NewRcvPK() ([]byte, error) {
+	if !n.Conf.WtConf.HasWt {
+		return nil, errors.New("node has no wallet")
+	}
+	return n.Wallet.NewRcvPK()
+}
+
+// NewRcvAddr (NewReceiveAddress) returns an address
+// of the node's wallet that hasn't been handed out
+// before, for someone to pay.
+// Returns:
+// string the address
+// error if the node has no wallet
+func (n *Node) NewRcvAddr() (string, error) {
+	if !n.Conf.WtConf.HasWt {
+		return "", errors.New("node has no wallet")
+	}
+	return n.Wallet.NewRcvAddr()
+}
+
+// WtchPK (WatchPublicKey) adds a public key for the
+// node's wallet to watch without being able to spend
+// its money (see wallet.Wallet.WtchPK).
+// Inputs:
+// pk []byte the serialized public key
+// Returns:
+// error if the node has no wallet or the public key
+// is invalid
+func (n *Node) WtchPK(pk []byte) error {
+	if !n.Conf.WtConf.HasWt {
+		return errors.New("node has no wallet")
+	}
+	return n.Wallet.WtchPK(pk)
+}
+
+// WtchScrpt (WatchScript) adds a locking script for
+// the node's wallet to watch (see
+// wallet.Wallet.WtchScrpt).
+// Inputs:
+// s string the locking script
+// Returns:
+// error if the node has no wallet or the locking
+// script is invalid
+func (n *Node) WtchScrpt(s string) error {
+	if !n.Conf.WtConf.HasWt {
+		return errors.New("node has no wallet")
+	}
+	return n.Wallet.WtchScrpt(s)
+}
+
+// MkUnsgndTx (MakeUnsignedTransaction) asks the
+// node's wallet to make a transaction paying
+// someone, without signing or sending it (see
+// wallet.Wallet.MkUnsgndTx).
+// Inputs:
+// amt uint32 the amount of money to pay
+// fee uint32 the fee of the transaction
+// pubK []byte the public key of the person to pay
+// Returns:
+// *tx.Transaction the unsigned transaction
+// error if the node has no wallet or the transaction
+// could not be made
+func (n *Node) MkUnsgndTx(amt uint32, fee uint32, pubK []byte) (*tx.Transaction, error) {
+	if !n.Conf.WtConf.HasWt {
+		return nil, errors.New("node has no wallet")
+	}
+	return n.Wallet.MkUnsgndTx(&wallet.TxReq{PubK: pubK, Amt: amt, Fee: fee})
+}
+
+// BckpWt (BackupWallet) returns the mnemonic that
+// the keys of the node's wallet are derived from.
+// Returns:
+// string the words of the mnemonic
+// error if the node has no wallet
+func (n *Node) BckpWt() (string, error) {
+	if !n.Conf.WtConf.HasWt {
+		return "", errors.New("node has no wallet")
+	}
+	return n.Wallet.Bckp(), nil
+}
+
+// RstrWt (RestoreWallet) restores the keys of the
+// node's wallet from a backed up mnemonic and finds
+// their coins on the chain.
+// Inputs:
+// mnem string the words of the mnemonic
+// pass string the passphrase, which may be empty
+// Returns:
+// error if the node has no wallet or the mnemonic is
+// invalid
+func (n *Node) RstrWt(mnem string, pass string) error {
+	if !n.Conf.WtConf.HasWt {
+		return errors.New("node has no wallet")
+	}
+	return n.Wallet.Rstr(mnem, pass)
+}
+
+// WtBal (WalletBalance) returns the balance of the
+// node's wallet across all of its keys.
+// Returns:
+// uint32 the balance, 0 if the node has no wallet
+func (n *Node) WtBal() uint32 {
+	if !n.Conf.WtConf.HasWt {
+		return 0
+	}
+	return n.Wallet.Bal()
+}
+
+// WtHist (WalletHistory) returns the transactions
+// that spend or pay the node's wallet (see
+// wallet.Wallet.Hist).
+// Returns:
+// []*wallet.Entry the wallet's transactions
+// error if the node has no wallet
+func (n *Node) WtHist() ([]*wallet.Entry, error) {
+	if !n.Conf.WtConf.HasWt {
+		return nil, errors.New("node has no wallet")
+	}
+	return n.Wallet.Hist(), nil
+}
+
+// WtBals (WalletBalances) returns the balances of
+// the node's wallet, split up by whether the money
+// can be spent (see wallet.Bals).
+// Returns:
+// *wallet.Bals the balances
+// error if the node has no wallet
+func (n *Node) WtBals() (*wallet.Bals, error) {
+	if !n.Conf.WtConf.HasWt {
+		return nil, errors.New("node has no wallet")
+	}
+	return n.Wallet.Bals(), nil
+}
+
+// WtTxStat (WalletTransactionStatus) returns the
+// status of a transaction sent by the node's wallet
+// (see wallet.TxStat).
+// Inputs:
+// h string the hash of the transaction
+// Returns:
+// *wallet.TxStat the status
+// error if the node has no wallet or the wallet
+// didn't send the transaction
+func (n *Node) WtTxStat(h string) (*wallet.TxStat, error) {
+	if !n.Conf.WtConf.HasWt {
+		return nil, errors.New("node has no wallet")
+	}
+	s, ok := n.Wallet.Trckr.Stat(h)
+	if !ok {
+		return nil, errors.New("transaction is not tracked by the wallet")
+	}
+	return s, nil
+}
+
+// SubTx (SubscribeTransaction) calls a function
+// whenever the status of a transaction sent by the
+// node's wallet changes (see wallet.TxTrckr.Sub).
+// Inputs:
+// h string the hash of the transaction, or "" for
+// every transaction
+// fn wallet.StatFn the function
+// Returns:
+// func() stops calling fn
+// error if the node has no wallet
+func (n *Node) SubTx(h string, fn wallet.StatFn) (func(), error) {
+	if !n.Conf.WtConf.HasWt {
+		return nil, errors.New("node has no wallet")
+	}
+	return n.Wallet.Trckr.Sub(h, fn), nil
+}
+
+// WtCoins (WalletCoins) returns the outputs that pay
+// the node's wallet, along with whether they can be
+// spent (see wallet.Wallet.ListCoins).
+// Returns:
+// []*wallet.Coin the outputs
+// error if the node has no wallet
+func (n *Node) WtCoins() ([]*wallet.Coin, error) {
+	if !n.Conf.WtConf.HasWt {
+		return nil, errors.New("node has no wallet")
+	}
+	return n.Wallet.ListCoins(), nil
+}
+
+// FrzCoin (FreezeCoin) stops the node's wallet from
+// spending one of its outputs (see wallet.Wallet.Frz).
+// Inputs:
+// loc string the locator of the output
+// Returns:
+// error if the node has no wallet or the output
+// doesn't pay it
+func (n *Node) FrzCoin(loc string) error {
+	if !n.Conf.WtConf.HasWt {
+		return errors.New("node has no wallet")
+	}
+	return n.Wallet.Frz(loc)
+}
+
+// UnfrzCoin (UnfreezeCoin) lets the node's wallet
+// spend an output it froze again.
+// Inputs:
+// loc string the locator of the output
+// Returns:
+// error if the node has no wallet
+func (n *Node) UnfrzCoin(loc string) error {
+	if !n.Conf.WtConf.HasWt {
+		return errors.New("node has no wallet")
+	}
+	n.Wallet.Unfrz(loc)
+	return nil
+}
+
+// Cnsldt (Consolidate) sends a transaction merging the
+// small outputs of the node's wallet into one (see
+// wallet.Wallet.Cnsldt).
+// Inputs:
+// ctx context.Context cancels waiting for the wallet
+// mxAmt uint32 the largest output to merge
+// rt fee.Rt the fee rate to pay, 0 to have it
+// estimated
+// Returns:
+// string the hash of the transaction
+// error if the node has no wallet or the outputs
+// could not be merged
+func (n *Node) Cnsldt(ctx context.Context, mxAmt uint32, rt fee.Rt) (string, error) {
+	if !n.Conf.WtConf.HasWt {
+		return "", errors.New("node has no wallet")
+	}
+	t, err := n.Wallet.Cnsldt(ctx, mxAmt, rt)
+	if err != nil {
+		return "", err
+	}
+	return t.Hash(), nil
+}
+
+// MkPSBT (MakePSBT) asks the node's wallet to make a
+// transaction paying someone as a PSBT, to be signed
+// elsewhere (see wallet.Wallet.MkPSBT).
+// Inputs:
+// amt uint32 the amount of money to pay
+// fee uint32 the fee of the transaction
+// pubK []byte the public key of the person to pay
+// Returns:
+// *wallet.PSBT the PSBT
+// error if the node has no wallet or the transaction
+// could not be made
+func (n *Node) MkPSBT(amt uint32, fee uint32, pubK []byte) (*wallet.PSBT, error) {
+	if !n.Conf.WtConf.HasWt {
+		return nil, errors.New("node has no wallet")
+	}
+	return n.Wallet.MkPSBT(&wallet.TxReq{PubK: pubK, Amt: amt, Fee: fee})
+}
+
+// SgnPSBT (SignPSBT) signs the inputs of a PSBT that
+// the node's wallet has the keys for.
+// Inputs:
+// p *wallet.PSBT the PSBT
+// Returns:
+// int the number of inputs signed
+// error if the node has no wallet, the wallet is
+// watch-only, or a key could not sign
+func (n *Node) SgnPSBT(p *wallet.PSBT) (int, error) {
+	if !n.Conf.WtConf.HasWt {
+		return 0, errors.New("node has no wallet")
+	}
+	return n.Wallet.SgnPSBT(p)
+}
+
+// BrdcstPSBT (BroadcastPSBT) finalizes a PSBT whose
+// inputs are all signed and sends its transaction to
+// the miner and the network. If it spends outputs of
+// the node's wallet, the wallet tracks it.
+// Inputs:
+// p *wallet.PSBT the PSBT
+// Returns:
+// string the hash of the transaction
+// error if the PSBT is not fully signed or its
+// transaction is invalid
+func (n *Node) BrdcstPSBT(p *wallet.PSBT) (string, error) {
+	t, err := p.Fnlz()
+	if err != nil {
+		return "", err
+	}
+	if !n.ChkTx(t) {
+		return "", errors.New("transaction is invalid")
+	}
+	if n.Conf.WtConf.HasWt {
+		n.Wallet.TrckPSBT(p, t)
+	}
+	n.HndlWtTx(t)
+	return t.Hash(), nil
+}
+
 // New returns a new Node object based on
 // a configuration
 // Inputs:
@@ -123,11 +536,30 @@ func New(conf *Config) *Node {
 	if conf.CstmID {
 		n.Id = conf.CstmIDObj
 	} else {
-		n.Id, _ = id.New(n.Conf.IdConf)
+		i, err := id.New(n.Conf.IdConf)
+		if err != nil {
+			panic(fmt.Sprintf("could not make id: %v", err))
+		}
+		n.Id = i
+	}
+	n.POW = pow.Get(n.Conf.ChainConf.POW)
+	if n.POW == nil {
+		panic(fmt.Sprintf("unknown proof of work algorithm %q", n.Conf.ChainConf.POW))
 	}
 	n.Chain = blockchain.New(n.Conf.ChainConf)
 	n.Wallet = wallet.New(n.Conf.WtConf, n.Id, n.Chain)
 	n.Mnr = miner.New(n.Conf.MnrConf, n.Id)
+	if n.Conf.MnrConf.HasMnr {
+		n.Mnr.POW = n.POW
+	}
+	n.FeeEst = fee.New(n.Conf.FeeConf)
+	if n.Conf.WtConf.HasWt {
+		n.Wallet.FeeEst = n.FeeEst
+		n.Wallet.MinRlyRt = n.Conf.MinRlyRt
+		if n.Conf.MnrConf.HasMnr {
+			n.Wallet.InPool = n.Mnr.TxP.Has
+		}
+	}
 
 	n.AddrDb = addressdb.New(true, 1000)
 	n.PeerDb = peer.NewDb(true, 200, "")
@@ -162,6 +594,14 @@ func (n *Node) Start() {
 		n.Wallet.SetAddr(addr)
 	}
 	n.StartServer(addr)
+	if n.Conf.MnrConf.HasMnr && n.Conf.MnrConf.TxPFile != "" {
+		es, err := miner.LdPoolFile(n.Conf.MnrConf.TxPFile)
+		if err != nil {
+			utils.Debug.Printf("%v could not load transaction pool file: %v", utils.FmtAddr(n.Addr), err)
+		}
+		n.svdTxs = es
+		n.RstrTxP()
+	}
 	go func() {
 		if n.Conf.MnrConf.HasMnr {
 			for {
@@ -191,20 +631,29 @@ func (n *Node) Start() {
 // seen blocks.
 // Inputs:
 // b *block.Block the block mined by the miner
-func (n *Node) HndlMnrBlk(b *block.Block) {
+// Returns:
+// *sync.WaitGroup done once every peer has been
+// sent the block
+func (n *Node) HndlMnrBlk(b *block.Block) *sync.WaitGroup {
 	n.BlockMapMutex.Lock()
 	n.BlockMap[b.Hash()] = true
 	n.BlockMapMutex.Unlock()
 	n.Chain.Add(b)
+	n.FeeEst.HndlBlk(b, n.Chain.Length()-1)
+	n.Mnr.Stats.ChkFnd(n.Chain)
 	if n.Conf.WtConf.HasWt {
 		blks := n.Chain.Slice(n.Chain.Length()-n.Conf.WtConf.SafeBlkAmt, n.Chain.Length())
 		if len(blks) == n.Conf.WtConf.SafeBlkAmt {
 			go n.Wallet.HndlBlk(blks[0])
 		}
+		n.Wallet.UpdtStats()
 	}
+	wg := &sync.WaitGroup{}
 	for _, p := range n.PeerDb.List() {
 		utils.Debug.Printf("%v sending %v to %v", utils.FmtAddr(n.Addr), b.NameTag(), utils.FmtAddr(p.Addr.Addr))
+		wg.Add(1)
 		go func(addr *address.Address) {
+			defer wg.Done()
 			_, err := addr.ForwardBlockRPC(b.Serialize())
 			if err != nil {
 				utils.Debug.Printf("%v recieved no response from ForwardBlockRPC to %v",
@@ -212,10 +661,42 @@ func (n *Node) HndlMnrBlk(b *block.Block) {
 			}
 		}(p.Addr)
 	}
+	return wg
+}
+
+// EstFeeRt (EstimateFeeRate) returns the fee rate
+// (fees per 1000 bytes) that a transaction should pay in
+// order to be mined within a certain number of blocks,
+// based on how long transactions have taken to be
+// mined so far.
+// Inputs:
+// trgt int the number of blocks the transaction
+// should be mined within
+// Returns:
+// fee.Rt the recommended fee rate
+// bool True if there was enough data for an estimate,
+// false otherwise
+func (n *Node) EstFeeRt(trgt int) (fee.Rt, bool) {
+	return n.FeeEst.EstFeeRt(trgt)
+}
+
+// MnrStatus (MinerStatus) returns a snapshot of
+// what the node's miner is doing and has done
+// (hashrate, templates built, blocks found, etc.).
+// Returns:
+// *miner.Status the miner's status, nil if the
+// node has no miner
+func (n *Node) MnrStatus() *miner.Status {
+	if !n.Conf.MnrConf.HasMnr {
+		return nil
+	}
+	n.Mnr.Stats.ChkFnd(n.Chain)
+	return n.Mnr.Status()
 }
 
 // GetBalance returns the balance (amount of money)
-// that someone currently has.
+// that someone currently has, paid either to their
+// public key or to its address (see txo.MkP2PKH).
 // Inputs:
 // pk string the public key of the person that the
 // balance wants to be known for.
@@ -223,7 +704,11 @@ func (n *Node) HndlMnrBlk(b *block.Block) {
 // uint32 the amount of money (the balance) that
 // the person with that public key has
 func (n *Node) GetBalance(pk string) uint32 {
-	return n.Chain.GetBalance(pk)
+	pkB, err := hex.DecodeString(pk)
+	if err != nil {
+		return n.Chain.GetBalance(pk)
+	}
+	return n.Chain.GetBalance(pk, txo.MkP2PKH(id.PKHsh(pkB)))
 }
 
 // HndlWtTx (HandleWalletTransaction) handles a new
@@ -236,9 +721,15 @@ func (n *Node) GetBalance(pk string) uint32 {
 // made by the wallet.
 func (n *Node) HndlWtTx(t *tx.Transaction) {
 	if n.Conf.MnrConf.HasMnr {
-		go n.Mnr.HndlTx(t)
+		go func() {
+			n.Mnr.HndlTx(t)
+			n.Wallet.UpdtStats()
+		}()
 	}
+	n.TxMapMutex.Lock()
 	n.TxMap[t.Hash()] = true
+	n.TxMapMutex.Unlock()
+	n.FeeEst.Add(t, n.Chain.Length()-1)
 	for _, p := range n.PeerDb.List() {
 		d := t.Serialize()
 		utils.Debug.Printf("%v sending %v to %v", utils.FmtAddr(n.Addr), t.NameTag(), utils.FmtAddr(p.Addr.Addr))
@@ -254,11 +745,49 @@ func (n *Node) HndlWtTx(t *tx.Transaction) {
 
 // StartMiner starts the miner, which means the miner
 // is now actively waiting for enough transactions
-// to mine.
+// to mine. A miner that was already started (or
+// killed) is left as it is.
 func (n *Node) StartMiner() {
 	n.Mnr.StartMiner()
 }
 
+// GenerateBlocks mines blocks right away out of the
+// transaction pool (even if it is empty), adds them to
+// the chain and broadcasts them. Each block is sent to
+// every peer before the next is made, so peers get them
+// in order. In regtest mode (see
+// RegtestConfig) the proof of work is trivial, so this
+// makes blocks instantly and deterministically.
+// Inputs:
+// ct int the number of blocks to generate
+// payTo []byte the public key the coinbase transactions
+// should pay, the node's own if nil
+// Returns:
+// []string the hashes of the generated blocks
+// error if the node has no miner or a nonce could not
+// be found
+func (n *Node) GenerateBlocks(ct int, payTo []byte) ([]string, error) {
+	if !n.Conf.MnrConf.HasMnr {
+		return nil, errors.New("node has no miner")
+	}
+	if payTo == nil {
+		payTo = n.Id.GetPublicKeyBytes()
+	}
+	hshs := make([]string, 0, ct)
+	for i := 0; i < ct; i++ {
+		b := n.Mnr.GenBlk(hex.EncodeToString(payTo))
+		if b == nil {
+			return hshs, errors.New("could not find a nonce for the block")
+		}
+		utils.Debug.Printf("%v generated %v %v", utils.FmtAddr(n.Addr), b.NameTag(), b.Summarize())
+		wg := n.HndlMnrBlk(b)
+		n.Mnr.HndlBlk(b)
+		wg.Wait()
+		hshs = append(hshs, b.Hash())
+	}
+	return hshs, nil
+}
+
 // This connects to a certain peer in the network. This just
 // serves as an interface for the real functionality contained
 // within the Router.
@@ -333,13 +862,72 @@ func (n *Node) Bootstrap() error {
 		n.BlockMap[b.Hash()] = true
 		n.BlockMapMutex.Unlock()
 		n.Chain.Add(b)
+		n.FeeEst.HndlBlk(b, n.Chain.Length()-1)
 		if chkOrf {
 			n.Mnr.HndlChkBlk(b)
 		}
 	}
+	n.RstrTxP()
+	n.Wallet.UpdtStats()
 	return nil
 }
 
+// RstrTxP (RestoreTransactionPool) revalidates the
+// transactions loaded from the transaction pool file
+// against the current UTXO and adds the valid ones
+// back to the miner's pool. Restored transactions are
+// tracked by the fee estimator from the height of the
+// chain when they first arrived, as if they had never
+// left the pool. Transactions that are not valid yet
+// (because the node has not caught up with the chain)
+// are kept to be tried again after the next
+// bootstrap, until they expire.
+func (n *Node) RstrTxP() {
+	if !n.Conf.MnrConf.HasMnr || len(n.svdTxs) == 0 {
+		return
+	}
+	blks := n.Chain.List()
+	var rst []*miner.PoolEntry
+	rstrd := 0
+	exp := n.Conf.MnrConf.TxPExp
+	for _, e := range n.svdTxs {
+		if exp > 0 && time.Since(e.Arrvd) > exp {
+			continue
+		}
+		t := tx.Deserialize(e.Tx)
+		if !n.ChkTx(t) {
+			rst = append(rst, e)
+			continue
+		}
+		if n.Mnr.TxP.AddAt(t, e.Arrvd) {
+			n.TxMapMutex.Lock()
+			n.TxMap[t.Hash()] = true
+			n.TxMapMutex.Unlock()
+			n.FeeEst.Add(t, hghtAt(blks, e.Arrvd))
+			rstrd++
+		}
+	}
+	utils.Debug.Printf("%v restored %v transactions to its pool, %v left", utils.FmtAddr(n.Addr), rstrd, len(rst))
+	n.svdTxs = rst
+}
+
+// hghtAt (heightAt) returns the height of the last
+// block of a chain that was made at or before a time.
+// Inputs:
+// blks []*block.Block the blocks of the main chain
+// tm time.Time the time
+// Returns:
+// int the height of the block, 0 (the genesis block)
+// if every other block was made later
+func hghtAt(blks []*block.Block, tm time.Time) int {
+	for i := len(blks) - 1; i > 0; i-- {
+		if int64(blks[i].Hdr.Timestamp) <= tm.Unix() {
+			return i
+		}
+	}
+	return 0
+}
+
 func (n *Node) StartServer(addr string) {
 	lis, err := net.Listen("tcp4", addr)
 	if err != nil {
@@ -373,7 +961,17 @@ func (n *Node) ResumeNetwork() {
 }
 
 // This kills any threads currently managed by the Node or that
-// it previously started. It also does any necessary clean up.
+// it previously started. It also does any necessary clean up,
+// such as stopping the miner and saving the transaction
+// pool to its file.
 func (n *Node) Kill() {
 	n.Server.GracefulStop()
+	if n.Conf.MnrConf.HasMnr {
+		n.Mnr.Kill()
+	}
+	if n.Conf.MnrConf.HasMnr && n.Conf.MnrConf.TxPFile != "" {
+		if err := n.Mnr.TxP.Dump(n.Conf.MnrConf.TxPFile); err != nil {
+			utils.Debug.Printf("%v could not save transaction pool file: %v", utils.FmtAddr(n.Addr), err)
+		}
+	}
 }
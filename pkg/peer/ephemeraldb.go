@@ -3,25 +3,33 @@ package peer
 import (
 	"errors"
 	"math/rand"
+	"sync"
 )
 
 type EphemeralPeerDb struct {
 	peers map[string]*Peer
 	limit int
 	Addr string
+	mutex sync.RWMutex
 }
 
 func (pdb *EphemeralPeerDb) In(k string) bool {
+	pdb.mutex.RLock()
+	defer pdb.mutex.RUnlock()
 	_, in := pdb.peers[k]
 	return in
 }
 
 func (pdb *EphemeralPeerDb) SetAddr(addr string) {
+	pdb.mutex.Lock()
+	defer pdb.mutex.Unlock()
 	pdb.Addr = addr
 }
 
 // Returns true if peer existed already or was added
 func (pdb *EphemeralPeerDb) Add(p *Peer) bool {
+	pdb.mutex.Lock()
+	defer pdb.mutex.Unlock()
 	oldP := pdb.peers[p.Addr.Addr]
 	if (oldP != nil && p.Addr.LastSeen != oldP.Addr.LastSeen) || (oldP == nil && len(pdb.peers) < pdb.limit) {
 		pdb.peers[p.Addr.Addr] = p
@@ -32,10 +40,14 @@ func (pdb *EphemeralPeerDb) Add(p *Peer) bool {
 }
 
 func (pdb *EphemeralPeerDb) Get(addr string) *Peer {
+	pdb.mutex.RLock()
+	defer pdb.mutex.RUnlock()
 	return pdb.peers[addr]
 }
 
 func (pdb *EphemeralPeerDb) UpdateLastSeen(addr string, lastSeen uint32) error {
+	pdb.mutex.Lock()
+	defer pdb.mutex.Unlock()
 	p := pdb.peers[addr]
 	if p == nil {
 		return errors.New("peer not found")
@@ -46,6 +58,8 @@ func (pdb *EphemeralPeerDb) UpdateLastSeen(addr string, lastSeen uint32) error {
 
 // Get up to n random peers
 func (pdb *EphemeralPeerDb) GetRandom(n int, exclude []string) []*Peer {
+	pdb.mutex.RLock()
+	defer pdb.mutex.RUnlock()
 	peers := make([]*Peer, 0)
 	if n >= len(pdb.peers) {
 		for _, peer := range pdb.peers {
@@ -72,6 +86,8 @@ func (pdb *EphemeralPeerDb) GetRandom(n int, exclude []string) []*Peer {
 }
 
 func (pdb *EphemeralPeerDb) List() []*Peer {
+	pdb.mutex.RLock()
+	defer pdb.mutex.RUnlock()
 	peers := make([]*Peer, 0)
 	for _, peer := range pdb.peers {
 		peers = append(peers, peer)
@@ -0,0 +1,133 @@
+package pow
+
+import (
+	"BrunoCoin/pkg/utils"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// Names of the proof of work algorithms, used
+// by the chain parameters to pick one.
+const (
+	SHA256Nm  = "sha256"
+	SHA256dNm = "sha256d"
+	ScryptNm  = "scrypt"
+	TrivialNm = "trivial"
+)
+
+// POW (ProofOfWork) is an algorithm that blocks
+// have to do work for before they can be added
+// to the chain.
+// Hash hashes a block header.
+// Satisfies checks whether a block header meets
+// a difficulty target.
+type POW interface {
+	Hash(hdr []byte) string
+	Satisfies(hdr []byte, dt string) bool
+}
+
+// Get returns the proof of work algorithm with
+// a certain name.
+// Inputs:
+// nm string the name of the algorithm
+// Returns:
+// POW the algorithm, nil if there is no
+// algorithm with that name
+func Get(nm string) POW {
+	switch nm {
+	case SHA256Nm, "":
+		return SHA256{}
+	case SHA256dNm:
+		return SHA256d{}
+	case ScryptNm:
+		return Scrypt{N: 1024, R: 1, P: 1}
+	case TrivialNm:
+		return Trivial{}
+	}
+	return nil
+}
+
+// Lss (Less) checks whether a hash is less than
+// a difficulty target.
+// Inputs:
+// hsh string the hash as a hex string
+// dt string the difficulty target as a hex string
+// Returns:
+// bool True if the hash is less than the target,
+// false otherwise (or if either can't be decoded)
+func Lss(hsh string, dt string) bool {
+	h, err := hex.DecodeString(hsh)
+	if err != nil {
+		fmt.Printf("ERROR {pow.Lss}: "+
+			"Could not decode hash {%v}.\n", hsh)
+		return false
+	}
+	difTrg, err := hex.DecodeString(dt)
+	if err != nil {
+		fmt.Printf("ERROR {pow.Lss}: "+
+			"Could not decode difficulty target {%v}.\n", dt)
+		return false
+	}
+	return bytes.Compare(h, difTrg) == -1
+}
+
+// SHA256 is a single SHA-256 of the header,
+// which is the same as the block's hash.
+type SHA256 struct{}
+
+func (SHA256) Hash(hdr []byte) string {
+	return utils.Hash(hdr)
+}
+
+func (p SHA256) Satisfies(hdr []byte, dt string) bool {
+	return Lss(p.Hash(hdr), dt)
+}
+
+// SHA256d is SHA-256 applied twice to the header.
+type SHA256d struct{}
+
+func (SHA256d) Hash(hdr []byte) string {
+	h := sha256.Sum256(hdr)
+	return utils.Hash(h[:])
+}
+
+func (p SHA256d) Satisfies(hdr []byte, dt string) bool {
+	return Lss(p.Hash(hdr), dt)
+}
+
+// Scrypt is the memory-hard scrypt function, with
+// the header as both the password and the salt.
+// N, R and P are scrypt's cost, block size and
+// parallelization parameters.
+type Scrypt struct {
+	N int
+	R int
+	P int
+}
+
+func (p Scrypt) Hash(hdr []byte) string {
+	h, err := utils.Scrypt(hdr, hdr, p.N, p.R, p.P, 32)
+	if err != nil {
+		fmt.Printf("ERROR {pow.Scrypt.Hash}: %v.\n", err)
+		return ""
+	}
+	return hex.EncodeToString(h)
+}
+
+func (p Scrypt) Satisfies(hdr []byte, dt string) bool {
+	return Lss(p.Hash(hdr), dt)
+}
+
+// Trivial accepts every header, so blocks can
+// be made instantly (for testing).
+type Trivial struct{}
+
+func (Trivial) Hash(hdr []byte) string {
+	return utils.Hash(hdr)
+}
+
+func (Trivial) Satisfies(hdr []byte, dt string) bool {
+	return true
+}
@@ -18,6 +18,7 @@ func SzOfTx(t *Transaction) uint32 {
 		sz += uint32(unsafe.Sizeof(txi.TransactionHash))
 		sz += uint32(unsafe.Sizeof(txi.UnlockingScript))
 		sz += uint32(unsafe.Sizeof(txi.OutputIndex))
+		sz += uint32(unsafe.Sizeof(txi.SequenceNumber))
 	}
 	for _, txo := range t.Outputs {
 		sz += uint32(unsafe.Sizeof(txo.Amount))
@@ -72,8 +73,21 @@ func SzOfHdr(h *BlockHeader) uint32 {
 }
 
 
+// FinalSeq (FinalSequence) is the sequence number
+// of a transaction input that does not allow its
+// transaction to be replaced.
+const FinalSeq uint32 = 0xFFFFFFFF
+
+// RBFSeq (ReplaceByFeeSequence) is the largest
+// sequence number of a transaction input that
+// signals its transaction may be replaced by one
+// paying a higher fee. The smallest is 1.
+const RBFSeq uint32 = 0xFFFFFFFD
+
 // NewTxInpt (NewTransactionInput) returns
-// a new protobuf transaction input.
+// a new protobuf transaction input. The input
+// has a final sequence number, so it does not
+// signal replace-by-fee.
 
 func NewTxInpt(h string, i uint32, unlckScr string, amt uint32) *TransactionInput {
 	return &TransactionInput{
@@ -81,6 +95,7 @@ func NewTxInpt(h string, i uint32, unlckScr string, amt uint32) *TransactionInpu
 		OutputIndex:     i,
 		UnlockingScript: unlckScr,
 		Amount:          amt,
+		SequenceNumber:  FinalSeq,
 	}
 }
 
@@ -4,6 +4,7 @@ import (
 	"BrunoCoin/pkg/address"
 	"BrunoCoin/pkg/block"
 	"BrunoCoin/pkg/block/tx"
+	"BrunoCoin/pkg/fee"
 	"BrunoCoin/pkg/peer"
 	"BrunoCoin/pkg/proto"
 	"BrunoCoin/pkg/utils"
@@ -157,18 +158,32 @@ func (n *Node) GetAddresses(ctx context.Context, in *proto.Empty) (*proto.Addres
 // Handles forward transaction request (tx propagation)
 func (n *Node) ForwardTransaction(ctx context.Context, in *proto.Transaction) (*proto.Empty, error) {
 	t := tx.Deserialize(in)
-	if n.TxMap[t.Hash()] {
+	n.TxMapMutex.Lock()
+	seen := n.TxMap[t.Hash()]
+	n.TxMapMutex.Unlock()
+	if seen {
 		return &proto.Empty{}, nil
 	}
 	if !n.ChkTx(t) {
 		utils.Debug.Printf("%v recieved invalid %v", utils.FmtAddr(n.Addr), t.NameTag())
 		return &proto.Empty{}, errors.New("transaction is not valid")
 	}
-	utils.Debug.Printf("%v recieved valid %v", utils.FmtAddr(n.Addr), t.NameTag())
-	if n.Conf.MnrConf.HasMnr {
-		go n.Mnr.HndlTx(t)
+	if rt := fee.FeeRt(t); rt < n.Conf.MinRlyRt {
+		utils.Debug.Printf("%v recieved %v paying %v, below the minimum relay rate %v",
+			utils.FmtAddr(n.Addr), t.NameTag(), rt, n.Conf.MinRlyRt)
+		return &proto.Empty{}, errors.New("transaction fee rate is below the minimum relay rate")
 	}
+	utils.Debug.Printf("%v recieved valid %v", utils.FmtAddr(n.Addr), t.NameTag())
+	n.TxMapMutex.Lock()
 	n.TxMap[t.Hash()] = true
+	n.TxMapMutex.Unlock()
+	// Transactions the pool turns away (such as replacements
+	// that don't pay enough) are not relayed
+	if n.Conf.MnrConf.HasMnr && !n.Mnr.HndlTx(t) {
+		utils.Debug.Printf("%v did not add %v to its pool", utils.FmtAddr(n.Addr), t.NameTag())
+		return &proto.Empty{}, nil
+	}
+	n.FeeEst.Add(t, n.Chain.Length()-1)
 	for _, p := range n.PeerDb.List() {
 		go func(addr *address.Address) {
 			_, err := addr.ForwardTransactionRPC(t.Serialize())
@@ -198,6 +213,12 @@ func (n *Node) ForwardBlock(ctx context.Context, in *proto.Block) (*proto.Empty,
 	}
 	mnChn := n.Chain.IsEndMainChain(b)
 	n.Chain.Add(b)
+	if mnChn {
+		n.FeeEst.HndlBlk(b, n.Chain.Length()-1)
+	}
+	if n.Conf.MnrConf.HasMnr {
+		n.Mnr.Stats.ChkFnd(n.Chain)
+	}
 	if n.Conf.MnrConf.HasMnr && mnChn {
 		go n.Mnr.HndlBlk(b)
 	}
@@ -206,6 +227,7 @@ func (n *Node) ForwardBlock(ctx context.Context, in *proto.Block) (*proto.Empty,
 		if len(blks) == n.Conf.WtConf.SafeBlkAmt {
 			go n.Wallet.HndlBlk(blks[0])
 		}
+		n.Wallet.UpdtStats()
 	}
 	for _, p := range n.PeerDb.List() {
 		go func(addr *address.Address) {
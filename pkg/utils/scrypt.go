@@ -0,0 +1,20 @@
+package utils
+
+import "golang.org/x/crypto/scrypt"
+
+// Scrypt derives a key from a password and a salt
+// using the memory-hard scrypt function (RFC 7914).
+// It uses about 128 * N * r bytes of memory.
+// Inputs:
+// pw []byte the password
+// salt []byte the salt
+// N int the cost parameter, a power of two above 1
+// r int the block size parameter
+// p int the parallelization parameter
+// kLn int the length of the key in bytes
+// Returns:
+// []byte the derived key
+// error if the parameters are invalid
+func Scrypt(pw, salt []byte, N, r, p, kLn int) ([]byte, error) {
+	return scrypt.Key(pw, salt, N, r, p, kLn)
+}
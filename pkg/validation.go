@@ -3,7 +3,7 @@ package pkg
 import (
 	"BrunoCoin/pkg/block"
 	"BrunoCoin/pkg/block/tx"
-	"BrunoCoin/pkg/block/tx/txi"
+	"BrunoCoin/pkg/block/tx/txo"
 )
 
 /*
@@ -44,6 +44,7 @@ import (
 // note: let b be a block object
 // t.IsCoinbase()
 // b.SatisfiesPOW(...)
+// n.POW
 // n.Conf.MxBlkSz
 // b.Sz()
 // n.Chain.ChkChainsUTXO(...)
@@ -61,7 +62,7 @@ func (n *Node) ChkBlk(b *block.Block) bool {
 		return false
 	}
 	// Verify hash is < difftarg
-	if !b.SatisfiesPOW(b.Hdr.DiffTarg) {
+	if !b.SatisfiesPOW(n.POW, b.Hdr.DiffTarg) {
 		return false
 	}
 	// Check block size
@@ -72,11 +73,16 @@ func (n *Node) ChkBlk(b *block.Block) bool {
 	if !n.Chain.ChkChainsUTXO(b.Transactions, b.Hdr.PrvBlkHsh) {
 		return false
 	}
-	// verify each transaction
-	for _, newTx := range b.Transactions {
-		if !n.ChkTx(newTx) {
+	// verify each transaction (besides the coinbase), letting
+	// transactions spend outputs made earlier on the block
+	blkUTXO := make(map[string]*txo.TransactionOutput)
+	for i, newTx := range b.Transactions {
+		if i > 0 && !n.chkTx(newTx, blkUTXO) {
 			return false
 		}
+		for j, o := range newTx.Outputs {
+			blkUTXO[txo.MkTXOLoc(newTx.Hash(), uint32(j))] = o
+		}
 	}
 	return true
 }
@@ -95,6 +101,8 @@ func (n *Node) ChkBlk(b *block.Block) bool {
 // The sum of the transaction's inputs must be larger
 // than the sum of the transaction's outputs.
 // The transaction must not double spend any UTXO.
+// The amount of each input must be the amount of the
+// UTXO it spends.
 // The unlocking script on each of the transaction's
 // inputs must successfully unlock each of the corresponding
 // UTXO.
@@ -113,6 +121,26 @@ func (n *Node) ChkBlk(b *block.Block) bool {
 // t.SumInputs()
 // t.SumOutputs()
 func (n *Node) ChkTx(t *tx.Transaction) bool {
+	return n.chkTx(t, nil)
+}
+
+// chkTx (checkTransaction) validates a transaction
+// the same way as ChkTx. The UTXO that the transaction
+// spends must either be on the main chain or, if
+// blkUTXO is nil, made by a transaction in the miner's
+// transaction pool, so that children of unmined
+// transactions are valid too. Otherwise, it may be
+// in blkUTXO (made by an earlier transaction on the
+// same block).
+// Inputs:
+// t *tx.Transaction the transaction to be checked for validity
+// blkUTXO map[string]*txo.TransactionOutput the outputs
+// made by earlier transactions on the block that t is on,
+// or nil if t is not on a block
+// Returns:
+// bool True if the transaction is valid. false
+// otherwise
+func (n *Node) chkTx(t *tx.Transaction, blkUTXO map[string]*txo.TransactionOutput) bool {
 	// Check that tx and its outputs are not nil or empty
 	if t == nil || t.Inputs == nil || t.Outputs == nil {
 		return false
@@ -129,22 +157,30 @@ func (n *Node) ChkTx(t *tx.Transaction) bool {
 		return false
 	}
 	// Check for double spending and verify locking scripts
-	doubleSpendingCheckMap := make(map[*txi.TransactionInput]bool)
-	for _, txInput := range t.Inputs {
+	doubleSpendingCheckMap := make(map[string]bool)
+	for j, txInput := range t.Inputs {
+		loc := txo.MkTXOLoc(txInput.TransactionHash, txInput.OutputIndex)
 		// check if its even valid
-		if n.Chain.IsInvalidInput(txInput) {
+		txOutput := n.Chain.GetUTXO(txInput)
+		if txOutput == nil && blkUTXO != nil {
+			txOutput = blkUTXO[loc]
+		} else if txOutput == nil && n.Conf.MnrConf.HasMnr {
+			txOutput = n.Mnr.TxP.GetUTXO(txInput)
+		}
+		// The fee is worked out from the amounts the inputs
+		// claim, so they must match the outputs spent
+		if txOutput == nil || txInput.Amount != txOutput.Amount {
 			return false
 		}
 		// check for double spending
-		if _, ok := doubleSpendingCheckMap[txInput]; ok {
+		if _, ok := doubleSpendingCheckMap[loc]; ok {
 			return false
 		}
 		// Check if unlocking script is good
-		txOutput := n.Chain.GetUTXO(txInput)
-		if !txOutput.IsUnlckd(txInput.UnlockingScript) {
+		if !txOutput.IsUnlckd(txInput.UnlockingScript, t.SigHsh(j)) {
 			return false
 		}
-		doubleSpendingCheckMap[txInput] = true
+		doubleSpendingCheckMap[loc] = true
 	}
 	return true
 }
@@ -0,0 +1,200 @@
+package wallet
+
+import (
+	"BrunoCoin/pkg/block/tx"
+	"BrunoCoin/pkg/fee"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Coin is an output of the wallet, along with what
+// decides whether the wallet spends it.
+// UTXO is the output. Its Hght is -1 if it was made
+// by a pending transaction.
+// Confs are the blocks on the main chain from the one
+// the output was made in to the last one, 0 while it
+// is pending.
+// Chng (Change) defines whether the output pays one
+// of the wallet's change keys.
+// Wtchd (Watched) defines whether the output pays a
+// watched key, which the wallet can't sign for.
+// Immtr (Immature) defines whether the output is from
+// a coinbase transaction with too few confirmations
+// (see Config.CbMtr).
+// Rsrvd (Reserved) defines whether a pending
+// transaction of the wallet already spends the output.
+// Frzn (Frozen) defines whether the output was frozen
+// (see Wallet.Frz).
+// Spndbl (Spendable) defines whether the wallet's
+// transactions may spend the output.
+type Coin struct {
+	*UTXO
+	Confs  int
+	Chng   bool
+	Wtchd  bool
+	Immtr  bool
+	Rsrvd  bool
+	Frzn   bool
+	Spndbl bool
+}
+
+// ListCoins returns the outputs that pay the wallet:
+// the ones on the main chain that aren't spent there,
+// oldest first, then the ones made by its pending
+// transactions.
+// Returns:
+// []*Coin the outputs
+func (w *Wallet) ListCoins() []*Coin {
+	us := w.Coins()
+	tip := w.UTXOs.Hght()
+	pndng := w.pndng(w.UTXOs.Hist())
+	rsrvd := spntBy(pndng)
+	for _, t := range pndng {
+		for j, o := range t.Outputs {
+			if w.Keys.Has(o.LockingScript) {
+				us = append(us, &UTXO{TxHsh: t.Hash(), OutIdx: uint32(j), Amt: o.Amount, LckScrpt: o.LockingScript, Hght: -1})
+			}
+		}
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	cs := make([]*Coin, 0, len(us))
+	for _, u := range us {
+		c := &Coin{
+			UTXO:  u,
+			Chng:  w.Keys.IsChng(u.LckScrpt),
+			Wtchd: w.Keys.Get(u.LckScrpt) == nil,
+			Immtr: w.immtr(u, tip),
+			Rsrvd: rsrvd[u.Loc()],
+			Frzn:  w.frzn[u.Loc()],
+		}
+		if u.Hght >= 0 {
+			c.Confs = tip - u.Hght + 1
+		}
+		c.Spndbl = w.cnSpnd(c, true)
+		cs = append(cs, c)
+	}
+	return cs
+}
+
+// cnSpnd (canSpend) returns whether the wallet's
+// transactions may spend an output: it must be
+// mature, unreserved and not frozen, and on the main
+// chain unless it is change and Conf.SpndUncnfrmd is
+// set.
+// Inputs:
+// c *Coin the output
+// sgnd bool whether the wallet must be able to sign
+// for the output
+// Returns:
+// bool True if the output may be spent
+func (w *Wallet) cnSpnd(c *Coin, sgnd bool) bool {
+	if c.Immtr || c.Rsrvd || c.Frzn || (sgnd && c.Wtchd) {
+		return false
+	}
+	return c.Hght >= 0 || (w.Conf.SpndUncnfrmd && c.Chng)
+}
+
+// Frz (Freeze) stops the wallet from spending one of
+// its outputs, such as one it wants to keep apart
+// from the others, until it is unfrozen.
+// Inputs:
+// loc string the locator of the output (see
+// txo.MkTXOLoc)
+// Returns:
+// error if the output doesn't pay the wallet
+func (w *Wallet) Frz(loc string) error {
+	for _, c := range w.ListCoins() {
+		if c.Loc() == loc {
+			w.mutex.Lock()
+			w.frzn[loc] = true
+			w.mutex.Unlock()
+			return nil
+		}
+	}
+	return fmt.Errorf("output %v does not pay the wallet", loc)
+}
+
+// Unfrz (Unfreeze) lets the wallet spend an output it
+// froze again.
+// Inputs:
+// loc string the locator of the output
+func (w *Wallet) Unfrz(loc string) {
+	w.mutex.Lock()
+	delete(w.frzn, loc)
+	w.mutex.Unlock()
+}
+
+// pick returns the outputs with the inputted
+// locators.
+// Inputs:
+// us []*UTXO the outputs that may be spent
+// locs []string the locators of the outputs to spend
+// Returns:
+// []*UTXO the outputs, in the order of locs
+// error if an output can't be spent or is chosen
+// twice
+func pick(us []*UTXO, locs []string) ([]*UTXO, error) {
+	byLoc := make(map[string]*UTXO, len(us))
+	for _, u := range us {
+		byLoc[u.Loc()] = u
+	}
+	pckd := make([]*UTXO, 0, len(locs))
+	seen := make(map[string]bool, len(locs))
+	for _, l := range locs {
+		u, ok := byLoc[l]
+		if !ok {
+			return nil, fmt.Errorf("output %v can not be spent", l)
+		}
+		if seen[l] {
+			return nil, fmt.Errorf("output %v is chosen twice", l)
+		}
+		seen[l] = true
+		pckd = append(pckd, u)
+	}
+	return pckd, nil
+}
+
+// Cnsldt (Consolidate) merges the wallet's small
+// outputs into one output paying a fresh change key,
+// so that later transactions need fewer inputs. It is
+// best done while fees are low. The smallest outputs
+// are merged first, at most Conf.MxCnsldt of them,
+// leaving out ones worth less than the fee of
+// spending them.
+// Inputs:
+// ctx context.Context cancels waiting for the node
+// mxAmt uint32 the largest output to merge
+// rt fee.Rt the fee rate to pay, 0 to have it
+// estimated
+// Returns:
+// *tx.Transaction the transaction that was sent
+// error if there are fewer than 2 outputs to merge,
+// or the transaction could not be made or sent (see
+// Snd)
+func (w *Wallet) Cnsldt(ctx context.Context, mxAmt uint32, rt fee.Rt) (*tx.Transaction, error) {
+	feeFn := w.feeFn(&TxReq{FeeRt: rt})
+	inFee := feeFn(2, 1) - feeFn(1, 1)
+	return w.snd(ctx, nil, func() (*tx.Transaction, error) {
+		var us []*UTXO
+		for _, u := range w.spndbl(true) {
+			if u.Amt <= mxAmt && u.Amt > inFee {
+				us = append(us, u)
+			}
+		}
+		sort.SliceStable(us, func(i, j int) bool { return us[i].Amt < us[j].Amt })
+		if len(us) > w.Conf.MxCnsldt {
+			us = us[:w.Conf.MxCnsldt]
+		}
+		if len(us) < 2 {
+			return nil, errors.New("fewer than 2 outputs to consolidate")
+		}
+		sel, err := SpndAll(us, 0, 0, feeFn, w.Conf.DustLim)
+		if err != nil || sel.Chng == 0 {
+			return nil, fmt.Errorf("could not pay the fee of consolidating: %w", ErrInsfFnds)
+		}
+		return w.bldTx(nil, sel, true)
+	})
+}
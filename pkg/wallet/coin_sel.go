@@ -0,0 +1,237 @@
+package wallet
+
+import (
+	"BrunoCoin/pkg/proto"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Strtgy (Strategy) is a way of choosing which
+// outputs a transaction spends.
+// LrgstFrst (LargestFirst) spends the largest outputs
+// first, which keeps transactions small.
+// BnB (BranchAndBound) searches for a set of outputs
+// that pays the amount and the fee without any change
+// worth keeping, so that no change output is needed.
+// If there is none, it falls back to LrgstFrst.
+// Rnd (Random) spends outputs in a random order, so
+// that which outputs get spent together says less
+// about who owns them.
+type Strtgy int
+
+const (
+	LrgstFrst Strtgy = iota
+	BnB
+	Rnd
+)
+
+// bnbTries is how many branches BnB looks at before
+// giving up on finding an exact match.
+const bnbTries = 100000
+
+// ErrInsfFnds (ErrorInsufficientFunds) is returned
+// when the wallet doesn't have enough money to pay
+// the amount and the fee.
+var ErrInsfFnds = errors.New("insufficient funds")
+
+// FeeFn (FeeFunction) returns the fee a transaction
+// with a number of inputs and outputs needs to pay.
+type FeeFn func(nIns int, nOuts int) uint32
+
+// CoinSel (CoinSelection) is the outcome of choosing
+// outputs to spend.
+// UTXOs are the outputs to spend.
+// Fee is the fee the transaction pays.
+// Chng (Change) is the amount of the change output,
+// 0 if there shouldn't be one.
+type CoinSel struct {
+	UTXOs []*UTXO
+	Fee   uint32
+	Chng  uint32
+}
+
+// TxSz (TransactionSize) returns the size in bytes of
+// a transaction made by the wallet with a number of
+// inputs and outputs.
+// Inputs:
+// nIns int the number of inputs
+// nOuts int the number of outputs
+// Returns:
+// uint32 the size of the transaction
+func TxSz(nIns int, nOuts int) uint32 {
+	t := proto.NewTx(0, make([]*proto.TransactionInput, nIns), make([]*proto.TransactionOutput, nOuts), 0)
+	for i := range t.Inputs {
+		t.Inputs[i] = &proto.TransactionInput{}
+	}
+	for i := range t.Outputs {
+		t.Outputs[i] = &proto.TransactionOutput{}
+	}
+	return proto.SzOfTx(t)
+}
+
+// SlctCoins (SelectCoins) chooses outputs to spend
+// on a transaction paying an amount. The fee is
+// worked out for the number of inputs chosen and
+// whether there is a change output. Change smaller
+// than dust isn't worth an output (it would cost
+// more to spend than it is worth), so it is added
+// to the fee instead.
+// Inputs:
+// utxos []*UTXO the outputs that may be spent
+// amt uint32 the amount to pay
+// nOuts int the number of outputs paying the amount,
+// not counting change
+// feeFn FeeFn the fee for a number of inputs and
+// outputs
+// dust uint32 the smallest change worth an output
+// s Strtgy the strategy to choose outputs with
+// Returns:
+// *CoinSel the outputs to spend, the fee and the
+// change
+// error ErrInsfFnds if the outputs can't pay the
+// amount and the fee
+func SlctCoins(utxos []*UTXO, amt uint32, nOuts int, feeFn FeeFn, dust uint32, s Strtgy) (*CoinSel, error) {
+	us := append([]*UTXO{}, utxos...)
+	switch s {
+	case BnB:
+		if cs := bnb(us, amt, nOuts, feeFn, dust); cs != nil {
+			return cs, nil
+		}
+		fallthrough
+	case LrgstFrst:
+		sort.SliceStable(us, func(i, j int) bool { return us[i].Amt > us[j].Amt })
+	case Rnd:
+		rand.Shuffle(len(us), func(i, j int) { us[i], us[j] = us[j], us[i] })
+	}
+	var sum uint64
+	for i, u := range us {
+		sum += uint64(u.Amt)
+		if cs := fnsh(us[:i+1], sum, amt, nOuts, feeFn, dust); cs != nil {
+			return cs, nil
+		}
+	}
+	return nil, ErrInsfFnds
+}
+
+// SpndAll (SpendAll) spends every output given on a
+// transaction paying an amount, such as outputs
+// chosen by hand, working out the fee and change as
+// SlctCoins does.
+// Inputs:
+// utxos []*UTXO the outputs to spend
+// amt uint32 the amount to pay
+// nOuts int the number of outputs paying the amount,
+// not counting change
+// feeFn FeeFn the fee for a number of inputs and
+// outputs
+// dust uint32 the smallest change worth an output
+// Returns:
+// *CoinSel the outputs, the fee and the change
+// error ErrInsfFnds if the outputs can't pay the
+// amount and the fee
+func SpndAll(utxos []*UTXO, amt uint32, nOuts int, feeFn FeeFn, dust uint32) (*CoinSel, error) {
+	var sum uint64
+	for _, u := range utxos {
+		sum += uint64(u.Amt)
+	}
+	if cs := fnsh(append([]*UTXO{}, utxos...), sum, amt, nOuts, feeFn, dust); cs != nil && len(utxos) > 0 {
+		return cs, nil
+	}
+	return nil, ErrInsfFnds
+}
+
+// fnsh (finish) works out the fee and change of
+// spending some outputs, if they are enough.
+// Inputs:
+// us []*UTXO the outputs to spend
+// sum uint64 the sum of the outputs
+// amt uint32 the amount to pay
+// nOuts int the number of outputs paying the amount
+// feeFn FeeFn the fee for a number of inputs and
+// outputs
+// dust uint32 the smallest change worth an output
+// Returns:
+// *CoinSel the selection, nil if the outputs aren't
+// enough
+func fnsh(us []*UTXO, sum uint64, amt uint32, nOuts int, feeFn FeeFn, dust uint32) *CoinSel {
+	if sum < uint64(amt)+uint64(feeFn(len(us), nOuts)) {
+		return nil
+	}
+	rst := sum - uint64(amt)
+	chngFee := uint64(feeFn(len(us), nOuts+1))
+	if rst > chngFee && rst >= chngFee+uint64(dust) && rst-chngFee <= math.MaxUint32 {
+		return &CoinSel{UTXOs: us, Fee: uint32(chngFee), Chng: uint32(rst - chngFee)}
+	}
+	if rst > math.MaxUint32 {
+		return nil
+	}
+	return &CoinSel{UTXOs: us, Fee: uint32(rst)}
+}
+
+// bnb (branchAndBound) searches for outputs whose sum
+// pays the amount and the fee of a transaction
+// without change, wasting less than dust on the fee.
+// Of the matches it looks at, the one wasting the
+// least is kept. Larger outputs are tried first, and
+// a branch is cut once its sum is enough or the
+// outputs left can't make it enough.
+// Inputs:
+// us []*UTXO the outputs that may be spent
+// amt uint32 the amount to pay
+// nOuts int the number of outputs paying the amount
+// feeFn FeeFn the fee for a number of inputs and
+// outputs
+// dust uint32 the smallest change worth an output
+// Returns:
+// *CoinSel the selection without change, nil if
+// none was found
+func bnb(us []*UTXO, amt uint32, nOuts int, feeFn FeeFn, dust uint32) *CoinSel {
+	sorted := append([]*UTXO{}, us...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Amt > sorted[j].Amt })
+	rmng := make([]uint64, len(sorted)+1)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		rmng[i] = rmng[i+1] + uint64(sorted[i].Amt)
+	}
+	tries := 0
+	var slctd, best []*UTXO
+	var bestWst uint64
+	var srch func(i int, sum uint64) bool
+	// srch returns true once an exact match is found,
+	// since nothing can beat it
+	srch = func(i int, sum uint64) bool {
+		tries++
+		if tries > bnbTries {
+			return true
+		}
+		if len(slctd) > 0 {
+			trgt := uint64(amt) + uint64(feeFn(len(slctd), nOuts))
+			if sum >= trgt {
+				if wst := sum - trgt; (wst == 0 || wst < uint64(dust)) && (best == nil || wst < bestWst) {
+					best, bestWst = append([]*UTXO{}, slctd...), wst
+				}
+				return best != nil && bestWst == 0
+			}
+		}
+		// The outputs left can't even pay the amount
+		if i == len(sorted) || sum+rmng[i] < uint64(amt) {
+			return false
+		}
+		slctd = append(slctd, sorted[i])
+		if srch(i+1, sum+uint64(sorted[i].Amt)) {
+			return true
+		}
+		slctd = slctd[:len(slctd)-1]
+		return srch(i+1, sum)
+	}
+	srch(0, 0)
+	if best == nil {
+		return nil
+	}
+	var sum uint64
+	for _, u := range best {
+		sum += uint64(u.Amt)
+	}
+	return &CoinSel{UTXOs: best, Fee: uint32(sum - uint64(amt))}
+}
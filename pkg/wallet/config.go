@@ -1,5 +1,10 @@
 package wallet
 
+import (
+	"BrunoCoin/pkg/fee"
+	"BrunoCoin/pkg/id"
+)
+
 /*
  *  Brown University, CS1951L, Summer 2021
  *  Designed by: Colby Anderson, Kotone Ninagawa
@@ -21,12 +26,66 @@ package wallet
 // software version of the node.
 // DefLckTm (DefaultLockTime) is the default lock
 // time (when the utxo can be spent)
+// RBF (ReplaceByFee) defines whether the wallet's
+// transactions signal that they may be replaced
+// by ones paying a higher fee (see
+// Wallet.BumpFee). It is off by default, so that
+// replacing has to be opted into.
+// FeeTrgt (FeeTarget) defines the number of blocks
+// that the wallet's transactions should be mined
+// within when it estimates their fees.
+// GapLim (GapLimit) defines how many unused keys
+// the wallet derives past the last key that was
+// paid, and so how far apart payments to its keys
+// can be and still be found.
+// MnemBits (MnemonicBits) defines the bits of
+// entropy in the mnemonic the wallet's keys are
+// made from (128 makes 12 words, 256 makes 24),
+// when the node's keystore doesn't have a seed yet.
+// CoinSel (CoinSelection) defines the strategy the
+// wallet uses to choose which of its outputs a
+// transaction spends (see Strtgy).
+// DustLim (DustLimit) defines the smallest change
+// the wallet pays itself. Smaller change is added
+// to the fee instead.
+// CbMtr (CoinbaseMaturity) defines how many
+// confirmations an output of a coinbase transaction
+// needs before the wallet spends it, in case its
+// block is left behind by a fork.
+// FallbkRt (FallbackRate) defines the fee rate the
+// wallet's transactions pay when no fee was asked
+// for and there isn't enough data to estimate one.
+// SpndUncnfrmd (SpendUnconfirmed) defines whether the
+// wallet's transactions may spend the change of its
+// transactions that aren't on the main chain yet.
+// MxCnsldt (MaxConsolidate) defines the most outputs
+// that one consolidation spends (see
+// Wallet.Cnsldt).
+// WtchOnly (WatchOnly) defines whether the wallet
+// has no keys of its own and only watches public
+// keys imported into it, never signing anything.
+// AddrVer (AddressVersion) defines the version of
+// the addresses the wallet hands out and pays, which
+// sets them apart from other networks' (see
+// id.EncdAddr).
 type Config struct {
 	HasWt			bool
 	TxRplyThresh 	uint32
 	SafeBlkAmt		int
 	TxVer			uint32
 	DefLckTm		uint32
+	RBF				bool
+	FeeTrgt			int
+	GapLim			uint32
+	MnemBits		int
+	CoinSel			Strtgy
+	DustLim			uint32
+	CbMtr			int
+	FallbkRt		fee.Rt
+	SpndUncnfrmd	bool
+	MxCnsldt		int
+	WtchOnly		bool
+	AddrVer			byte
 }
 
 
@@ -39,6 +98,18 @@ func DefaultConfig() *Config {
 		SafeBlkAmt:		5,
 		TxVer:			0,
 		DefLckTm:		0,
+		RBF:			false,
+		FeeTrgt:		3,
+		GapLim:			20,
+		MnemBits:		128,
+		CoinSel:		BnB,
+		DustLim:		5,
+		CbMtr:			5,
+		FallbkRt:		20,
+		SpndUncnfrmd:	false,
+		MxCnsldt:		50,
+		WtchOnly:		false,
+		AddrVer:		id.MainAddrVer,
 	}
 }
 
@@ -51,5 +122,17 @@ func NilConfig() *Config {
 		SafeBlkAmt:		0,
 		TxVer:			0,
 		DefLckTm:		0,
+		RBF:			false,
+		FeeTrgt:		0,
+		GapLim:			0,
+		MnemBits:		0,
+		CoinSel:		LrgstFrst,
+		DustLim:		0,
+		CbMtr:			0,
+		FallbkRt:		0,
+		SpndUncnfrmd:	false,
+		MxCnsldt:		0,
+		WtchOnly:		false,
+		AddrVer:		0,
 	}
 }
\ No newline at end of file
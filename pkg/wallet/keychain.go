@@ -0,0 +1,378 @@
+package wallet
+
+import (
+	"BrunoCoin/pkg/block/tx/txo"
+	"BrunoCoin/pkg/blockchain"
+	"BrunoCoin/pkg/id"
+	"encoding/hex"
+	"sync"
+)
+
+// Brnch (Branch) is a branch of the keychain. Keys
+// handed out to be paid by others are on the
+// receive branch (Rcv) and keys that the wallet pays
+// its own change to are on the change branch (Chng).
+type Brnch uint32
+
+const (
+	Rcv Brnch = iota
+	Chng
+)
+
+// key is a key the wallet can spend with, or only
+// watch.
+// Id is the id holding the key, nil for keys that
+// are only watched.
+// Brnch is the branch the key was derived on.
+// Idx is the index on the branch, -1 for keys that
+// were imported instead of derived.
+type key struct {
+	Id    id.ID
+	Brnch Brnch
+	Idx   int64
+}
+
+// brnch (branch) keeps track of the keys derived
+// on one branch of the keychain.
+// Key is the key of the branch.
+// drvd (derived) are the keys derived so far, by
+// index. An index without a valid key is nil.
+// nxt (next) is the index of the next key to hand
+// out.
+// lstUsd (lastUsed) is the index of the last key
+// seen paid on the chain, -1 if none were.
+type brnch struct {
+	Key    *id.HDKey
+	drvd   []id.ID
+	nxt    int64
+	lstUsd int64
+}
+
+// Keychain is the wallet's set of keys. Keys are
+// derived from a master key (see id.HDKey) on the
+// path m/0'/branch/index, so that every payment
+// uses a fresh key and all of them can be recreated
+// from the seed. The keys after the last one used
+// are derived ahead of time, up to GapLim of them,
+// so that payments to them are recognized.
+// Mstr (Master) is the master key, nil for a
+// watch-only keychain, which only has keys that were
+// imported.
+// GapLim (GapLimit) is how many unused keys are
+// derived past the last used key on each branch.
+// brnchs (branches) are the receive and change
+// branches.
+// keys maps the locking scripts paying every key the
+// wallet has to the key: its hex encoded public key
+// and its pay-to-public-key-hash script.
+type Keychain struct {
+	Mstr   *id.HDKey
+	GapLim uint32
+
+	brnchs [2]*brnch
+	keys   map[string]*key
+	mutex  sync.Mutex
+}
+
+// NewKeychain makes a keychain out of a seed.
+// Inputs:
+// seed []byte the seed of the master key
+// gapLim uint32 how many unused keys to derive
+// past the last used key
+// Returns:
+// *Keychain the keychain
+// error if the seed doesn't make a valid master key
+func NewKeychain(seed []byte, gapLim uint32) (*Keychain, error) {
+	kc := &Keychain{GapLim: gapLim, keys: make(map[string]*key)}
+	if err := kc.SetSeed(seed); err != nil {
+		return nil, err
+	}
+	return kc, nil
+}
+
+// NewWtchKeychain (NewWatchKeychain) makes a
+// watch-only keychain, which has no seed and can't
+// hand out keys. Keys are added with Wtch.
+// Returns:
+// *Keychain the keychain
+func NewWtchKeychain() *Keychain {
+	return &Keychain{keys: make(map[string]*key)}
+}
+
+// NewMnemKeychain (NewMnemonicKeychain) makes a
+// keychain out of a mnemonic (see id.MnemToSeed).
+// Inputs:
+// mnem string the words of the mnemonic
+// pass string the passphrase, which may be empty
+// gapLim uint32 how many unused keys to derive
+// past the last used key
+// Returns:
+// *Keychain the keychain
+// error if the mnemonic is invalid
+func NewMnemKeychain(mnem string, pass string, gapLim uint32) (*Keychain, error) {
+	seed, err := id.MnemToSeed(mnem, pass)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeychain(seed, gapLim)
+}
+
+// NewRndKeychain (NewRandomKeychain) makes a
+// keychain out of a random mnemonic with no
+// passphrase.
+// Inputs:
+// bits int the bits of entropy of the mnemonic
+// gapLim uint32 how many unused keys to derive
+// past the last used key
+// Returns:
+// *Keychain the keychain
+// string the words of the mnemonic
+// error if bits is invalid or there was not enough
+// randomness
+func NewRndKeychain(bits int, gapLim uint32) (*Keychain, string, error) {
+	mnem, err := id.NewMnem(bits)
+	if err != nil {
+		return nil, "", err
+	}
+	kc, err := NewMnemKeychain(mnem, "", gapLim)
+	return kc, mnem, err
+}
+
+// SetSeed replaces the keys derived from the old seed
+// with ones derived from a new seed. Imported keys
+// are kept.
+// Inputs:
+// seed []byte the seed of the master key
+// Returns:
+// error if the seed doesn't make a valid master key
+func (kc *Keychain) SetSeed(seed []byte) error {
+	mstr, err := id.NewMstrKey(seed)
+	if err != nil {
+		return err
+	}
+	acct, err := mstr.Chld(id.Hrdnd)
+	if err != nil {
+		return err
+	}
+	var brnchs [2]*brnch
+	for b := range brnchs {
+		k, err := acct.Chld(uint32(b))
+		if err != nil {
+			return err
+		}
+		brnchs[b] = &brnch{Key: k, lstUsd: -1}
+	}
+	kc.mutex.Lock()
+	defer kc.mutex.Unlock()
+	for pk, k := range kc.keys {
+		if k.Idx >= 0 {
+			delete(kc.keys, pk)
+		}
+	}
+	kc.Mstr, kc.brnchs = mstr, brnchs
+	for b := range kc.brnchs {
+		kc.fill(Brnch(b))
+	}
+	return nil
+}
+
+// Imprt (Import) adds a key that wasn't derived from
+// the seed, such as the node's own id, which the
+// miner pays.
+// Inputs:
+// i id.ID the id holding the key
+func (kc *Keychain) Imprt(i id.ID) {
+	kc.mutex.Lock()
+	defer kc.mutex.Unlock()
+	kc.add(hex.EncodeToString(i.GetPublicKeyBytes()), &key{Id: i, Brnch: Rcv, Idx: -1})
+}
+
+// Wtch (Watch) adds a key that the keychain doesn't
+// have the private key of, so that the outputs it is
+// paid are tracked but can't be spent.
+// Inputs:
+// pk string the hex encoded public key, or a
+// pay-to-public-key-hash script
+func (kc *Keychain) Wtch(pk string) {
+	kc.mutex.Lock()
+	defer kc.mutex.Unlock()
+	if _, ok := kc.keys[pk]; !ok {
+		kc.add(pk, &key{Brnch: Rcv, Idx: -1})
+	}
+}
+
+// IsWtchOnly (IsWatchOnly) returns whether the
+// keychain has no seed.
+func (kc *Keychain) IsWtchOnly() bool {
+	kc.mutex.Lock()
+	defer kc.mutex.Unlock()
+	return kc.Mstr == nil
+}
+
+// NewKey returns a key on a branch that hasn't been
+// handed out before.
+// Inputs:
+// b Brnch the branch to take the key from
+// Returns:
+// id.ID the fresh key, nil if the keychain is
+// watch-only
+func (kc *Keychain) NewKey(b Brnch) id.ID {
+	kc.mutex.Lock()
+	defer kc.mutex.Unlock()
+	if kc.Mstr == nil {
+		return nil
+	}
+	br := kc.brnchs[b]
+	for {
+		i := br.nxt
+		br.nxt++
+		kc.fill(b)
+		if br.drvd[i] != nil {
+			return br.drvd[i]
+		}
+	}
+}
+
+// Get returns the key that a locking script pays.
+// Inputs:
+// pk string the locking script (see Wtch)
+// Returns:
+// id.ID the key, nil if the wallet doesn't have it
+// or only watches it
+func (kc *Keychain) Get(pk string) id.ID {
+	kc.mutex.Lock()
+	defer kc.mutex.Unlock()
+	if k, ok := kc.keys[pk]; ok {
+		return k.Id
+	}
+	return nil
+}
+
+// Has returns whether a locking script pays one of
+// the keychain's keys, including watched ones.
+// Inputs:
+// pk string the locking script (see Wtch)
+func (kc *Keychain) Has(pk string) bool {
+	kc.mutex.Lock()
+	defer kc.mutex.Unlock()
+	_, ok := kc.keys[pk]
+	return ok
+}
+
+// IsChng (IsChange) returns whether a locking script
+// pays one of the wallet's change keys.
+// Inputs:
+// pk string the locking script (see Wtch)
+func (kc *Keychain) IsChng(pk string) bool {
+	kc.mutex.Lock()
+	defer kc.mutex.Unlock()
+	k, ok := kc.keys[pk]
+	return ok && k.Brnch == Chng
+}
+
+// PKs (PublicKeys) returns the hex encoded public
+// keys of every key the wallet has, including the
+// ones derived ahead of time and watched ones.
+func (kc *Keychain) PKs() []string {
+	kc.mutex.Lock()
+	defer kc.mutex.Unlock()
+	pks := make([]string, 0, len(kc.keys))
+	for pk := range kc.keys {
+		if _, ok := txo.PrsP2PKH(pk); !ok {
+			pks = append(pks, pk)
+		}
+	}
+	return pks
+}
+
+// MrkUsd (MarkUsed) records that a key was paid on
+// the chain. Keys before it are no longer handed out
+// and more keys are derived ahead of it.
+// Inputs:
+// pk string the locking script that was paid
+// Returns:
+// bool True if the key is a derived key past the
+// last key known to be used on its branch
+func (kc *Keychain) MrkUsd(pk string) bool {
+	kc.mutex.Lock()
+	defer kc.mutex.Unlock()
+	k, ok := kc.keys[pk]
+	if !ok || k.Idx < 0 {
+		return false
+	}
+	br := kc.brnchs[k.Brnch]
+	if k.Idx <= br.lstUsd {
+		return false
+	}
+	br.lstUsd = k.Idx
+	if br.nxt <= k.Idx {
+		br.nxt = k.Idx + 1
+	}
+	kc.fill(k.Brnch)
+	return true
+}
+
+// Dscvr (Discover) finds the keys that were paid on
+// the main chain, so that a keychain made from an
+// old seed picks up where it left off. The chain is
+// scanned again whenever a used key is found, since
+// more keys are derived ahead of it.
+// Inputs:
+// bc *blockchain.Blockchain the chain to scan
+// Returns:
+// bool True if any derived keys were found paid
+func (kc *Keychain) Dscvr(bc *blockchain.Blockchain) bool {
+	usd := false
+	for fnd := true; fnd; usd = usd || fnd {
+		fnd = false
+		for _, b := range bc.List() {
+			for _, t := range b.Transactions {
+				for _, o := range t.Outputs {
+					if kc.MrkUsd(o.LockingScript) {
+						fnd = true
+					}
+				}
+			}
+		}
+	}
+	return usd
+}
+
+// fill derives keys on a branch until there are
+// GapLim of them past both the last used key and
+// the last key handed out. Callers must hold
+// kc.mutex.
+// Inputs:
+// b Brnch the branch to derive keys on
+func (kc *Keychain) fill(b Brnch) {
+	br := kc.brnchs[b]
+	end := br.lstUsd + 1
+	if br.nxt > end {
+		end = br.nxt
+	}
+	end += int64(kc.GapLim)
+	for i := int64(len(br.drvd)); i < end; i++ {
+		var k id.ID
+		if c, err := br.Key.Chld(uint32(i)); err == nil {
+			if smpl, err := c.ID(); err == nil {
+				k = smpl
+				kc.add(hex.EncodeToString(smpl.GetPublicKeyBytes()), &key{Id: smpl, Brnch: b, Idx: i})
+			}
+		}
+		br.drvd = append(br.drvd, k)
+	}
+}
+
+// add adds a key under a locking script and, if the
+// script is a public key, under the
+// pay-to-public-key-hash script of that key too.
+// Callers must hold kc.mutex.
+// Inputs:
+// s string the locking script
+// k *key the key
+func (kc *Keychain) add(s string, k *key) {
+	kc.keys[s] = k
+	if pk, err := hex.DecodeString(s); err == nil {
+		kc.keys[txo.MkP2PKH(id.PKHsh(pk))] = k
+	}
+}
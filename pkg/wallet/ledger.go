@@ -0,0 +1,126 @@
+package wallet
+
+import (
+	"BrunoCoin/pkg/block/tx"
+	"BrunoCoin/pkg/block/tx/txo"
+)
+
+// Dir (Direction) is which way a transaction moved
+// the wallet's money.
+// In is a transaction paying the wallet that it
+// didn't fund.
+// Out is a transaction the wallet funded that pays
+// someone else.
+// Self is a transaction the wallet funded that only
+// pays the wallet's own keys.
+type Dir int
+
+const (
+	In Dir = iota
+	Out
+	Self
+)
+
+// String returns the name of the direction.
+func (d Dir) String() string {
+	switch d {
+	case In:
+		return "in"
+	case Out:
+		return "out"
+	case Self:
+		return "self"
+	}
+	return "unknown"
+}
+
+// Entry is a transaction in the wallet's history.
+// TxHsh is the hash of the transaction.
+// Dir is which way the transaction moved money.
+// Amt is the amount paid to others for Out, and the
+// amount received by the wallet for In and Self.
+// Fee is the fee the wallet paid, 0 for In.
+// Cntrprty (Counterparty) are the locking scripts
+// paid by the wallet for Out, and the wallet's keys
+// that were paid for In and Self.
+// Hght is the index of the block the transaction is
+// on, -1 while it is pending.
+// Confs are the blocks on the main chain from the
+// one the transaction is on to the last one, 0 while
+// it is pending.
+// Cb defines whether the transaction is a coinbase
+// transaction.
+type Entry struct {
+	TxHsh    string
+	Dir      Dir
+	Amt      uint32
+	Fee      uint32
+	Cntrprty []string
+	Hght     int
+	Confs    int
+	Cb       bool
+}
+
+// Bals (Balances) is how the wallet's money is split
+// up by whether it can be spent.
+// Cnfrmd (Confirmed) is money on the main chain that
+// can be spent.
+// Uncnfrmd (Unconfirmed) is money paid to the wallet
+// by its pending transactions, such as change.
+// Immtr (Immature) is money paid to the wallet by
+// coinbase transactions with fewer than
+// Conf.CbMtr confirmations, which can't be spent yet.
+// Lckd (Locked) is money on the main chain that is
+// spent by the wallet's pending transactions.
+type Bals struct {
+	Cnfrmd   uint32
+	Uncnfrmd uint32
+	Immtr    uint32
+	Lckd     uint32
+}
+
+// mkEnt (makeEntry) makes the history entry for a
+// transaction, if it involves the wallet.
+// Inputs:
+// t *tx.Transaction the transaction
+// hght int the index of the block the transaction
+// is on, -1 if it is pending
+// own func(string) (uint32, bool) returns the amount
+// of one of the wallet's outputs from its locator,
+// and whether the output is the wallet's
+// kc *Keychain the wallet's keys
+// Returns:
+// *Entry the entry, nil if the transaction neither
+// spends nor pays the wallet's outputs
+func mkEnt(t *tx.Transaction, hght int, own func(string) (uint32, bool), kc *Keychain) *Entry {
+	var sent, rcvd, othr uint32
+	var mine, thrs []string
+	for _, i := range t.Inputs {
+		if a, ok := own(txo.MkTXOLoc(i.TransactionHash, i.OutputIndex)); ok {
+			sent += a
+		}
+	}
+	for _, o := range t.Outputs {
+		if kc.Has(o.LockingScript) {
+			rcvd += o.Amount
+			mine = append(mine, o.LockingScript)
+		} else {
+			othr += o.Amount
+			thrs = append(thrs, o.LockingScript)
+		}
+	}
+	e := &Entry{TxHsh: t.Hash(), Hght: hght, Cb: t.IsCoinbase()}
+	switch {
+	case sent == 0 && rcvd == 0:
+		return nil
+	case sent == 0:
+		e.Dir, e.Amt, e.Cntrprty = In, rcvd, mine
+		return e
+	case othr > 0:
+		e.Dir, e.Amt, e.Cntrprty = Out, othr, thrs
+	default:
+		e.Dir, e.Amt, e.Cntrprty = Self, rcvd, mine
+	}
+	e.Fee = t.Fee()
+	return e
+}
@@ -76,12 +76,38 @@ func (l *LiminalTxs) ChkTxs(txs []*tx.Transaction) ([]*tx.Transaction, []*tx.Tra
 	// Increment all priorties
 	l.TxQ.IncAll()
 	// 3. Remove transactions above a certain priority threshold
-	aboveThres := l.TxQ.RemAbv(l.TxRplyThresh)
+	aboveThres := l.TxQ.RemAbv(uint64(l.TxRplyThresh))
 	l.mutex.Unlock()
 	return aboveThres, removedTransactions
 }
 
 
+// Rply (Replay) checks the transactions from a new
+// block like ChkTxs, but puts the transactions with
+// too large of a priority straight back with a
+// priority of 0, so that they are never missing from
+// the liminal transactions (and the outputs they
+// spend are never free to spend again) while they
+// are sent out again.
+// Inputs:
+// txs []*tx.Transaction a list of transactions that were in
+// a valid block
+// Returns:
+// []*tx.Transaction transactions with priorities above
+// l.TxRplyThresh, to be sent out again
+func (l *LiminalTxs) Rply(txs []*tx.Transaction) []*tx.Transaction {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.TxQ.Rmv(txs)
+	l.TxQ.IncAll()
+	old := l.TxQ.RemAbv(uint64(l.TxRplyThresh))
+	for _, t := range old {
+		l.TxQ.Add(0, t)
+	}
+	return old
+}
+
+
 // Add adds a transaction to the liminal transactions.
 // It is basically a wrapper around the heap add. The
 // priority is 0, since the transaction was just made
@@ -103,3 +129,64 @@ func (l *LiminalTxs) Add(t *tx.Transaction) {
 	l.mutex.Unlock()
 	return
 }
+
+
+// Get returns the liminal transaction with
+// the inputted hash.
+// Inputs:
+// h string the hash of the transaction
+// Returns:
+// *tx.Transaction the transaction, or nil if
+// it is not liminal
+func (l *LiminalTxs) Get(h string) *tx.Transaction {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, n := range *l.TxQ {
+		if n.T.Hash() == h {
+			return n.T
+		}
+	}
+	return nil
+}
+
+
+// List returns the liminal transactions.
+// Returns:
+// []*tx.Transaction the transactions, in no
+// particular order
+func (l *LiminalTxs) List() []*tx.Transaction {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	txs := make([]*tx.Transaction, 0, len(*l.TxQ))
+	for _, n := range *l.TxQ {
+		txs = append(txs, n.T)
+	}
+	return txs
+}
+
+
+// Rmv (Remove) removes a liminal transaction, such
+// as one that can no longer be mined, so that it is
+// not sent out again.
+// Inputs:
+// t *tx.Transaction the transaction to be removed
+func (l *LiminalTxs) Rmv(t *tx.Transaction) {
+	l.mutex.Lock()
+	l.TxQ.Rmv([]*tx.Transaction{t})
+	l.mutex.Unlock()
+}
+
+
+// Rplc (Replace) swaps a liminal transaction for
+// the transaction replacing it. The replacement
+// starts over with a priority of 0, since it was
+// just made.
+// Inputs:
+// old *tx.Transaction the transaction being replaced
+// t *tx.Transaction the replacement
+func (l *LiminalTxs) Rplc(old *tx.Transaction, t *tx.Transaction) {
+	l.mutex.Lock()
+	l.TxQ.Rmv([]*tx.Transaction{old})
+	l.TxQ.Add(0, t)
+	l.mutex.Unlock()
+}
@@ -0,0 +1,281 @@
+package wallet
+
+import (
+	"BrunoCoin/pkg/block/tx"
+	"BrunoCoin/pkg/block/tx/txo"
+	"BrunoCoin/pkg/id"
+	"BrunoCoin/pkg/proto"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// PSBT (PartiallySignedTransaction) is a transaction
+// that is passed around to be signed, such as from an
+// online watch-only wallet that makes it to an offline
+// machine that has the keys and back. Along with the
+// unsigned transaction, it carries the outputs that the
+// transaction spends, so that a signer doesn't need
+// the chain, and the signatures collected so far.
+// Ver (Version) is the version of the transaction.
+// LckTm (LockTime) is the lock time of the transaction.
+// Ins (Inputs) are the inputs of the transaction.
+// Outs (Outputs) are the outputs of the transaction.
+type PSBT struct {
+	Ver   uint32     `json:"version"`
+	LckTm uint32     `json:"lockTime"`
+	Ins   []*PSBTIn  `json:"inputs"`
+	Outs  []*PSBTOut `json:"outputs"`
+}
+
+// PSBTIn (PSBTInput) is an input of a PSBT.
+// TxHsh is the hash of the transaction of the output
+// spent.
+// OutIdx is the index of the output spent.
+// Seq (Sequence) is the sequence number of the input.
+// Amt is the amount of the output spent.
+// LckScrpt is the locking script of the output spent,
+// which says whose key must sign.
+// Sig (Signature) is the unlocking script, empty until
+// the input is signed.
+type PSBTIn struct {
+	TxHsh    string `json:"txHash"`
+	OutIdx   uint32 `json:"outputIndex"`
+	Seq      uint32 `json:"sequence"`
+	Amt      uint32 `json:"amount"`
+	LckScrpt string `json:"lockingScript"`
+	Sig      string `json:"signature,omitempty"`
+}
+
+// PSBTOut (PSBTOutput) is an output of a PSBT.
+// Amt is the amount paid.
+// LckScrpt is the locking script paid.
+type PSBTOut struct {
+	Amt      uint32 `json:"amount"`
+	LckScrpt string `json:"lockingScript"`
+}
+
+// NewPSBT makes a PSBT out of a transaction and the
+// outputs it spends. Signatures already on the
+// transaction are kept.
+// Inputs:
+// t *tx.Transaction the transaction
+// spnt []*txo.TransactionOutput the outputs spent by
+// the inputs of t, in the same order
+// Returns:
+// *PSBT the PSBT
+// error if an output spent is missing or doesn't
+// match the amount of its input
+func NewPSBT(t *tx.Transaction, spnt []*txo.TransactionOutput) (*PSBT, error) {
+	if len(spnt) != len(t.Inputs) {
+		return nil, errors.New("every input needs the output it spends")
+	}
+	p := &PSBT{Ver: t.Version, LckTm: t.LockTime}
+	for j, i := range t.Inputs {
+		if spnt[j] == nil || spnt[j].Amount != i.Amount {
+			return nil, fmt.Errorf("input %v does not match the output it spends", j)
+		}
+		p.Ins = append(p.Ins, &PSBTIn{
+			TxHsh:    i.TransactionHash,
+			OutIdx:   i.OutputIndex,
+			Seq:      i.SequenceNumber,
+			Amt:      i.Amount,
+			LckScrpt: spnt[j].LockingScript,
+			Sig:      i.UnlockingScript,
+		})
+	}
+	for _, o := range t.Outputs {
+		p.Outs = append(p.Outs, &PSBTOut{Amt: o.Amount, LckScrpt: o.LockingScript})
+	}
+	return p, nil
+}
+
+// DecdPSBT (DecodePSBT) reads a PSBT written by Encd.
+// Inputs:
+// s string the encoded PSBT
+// Returns:
+// *PSBT the PSBT
+// error if s isn't a valid PSBT
+func DecdPSBT(s string) (*PSBT, error) {
+	d, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &PSBT{}
+	if err := json.Unmarshal(d, p); err != nil {
+		return nil, err
+	}
+	if len(p.Ins) == 0 || len(p.Outs) == 0 {
+		return nil, errors.New("PSBT has no inputs or no outputs")
+	}
+	for j, i := range p.Ins {
+		if i == nil {
+			return nil, fmt.Errorf("PSBT input %v is empty", j)
+		}
+	}
+	for j, o := range p.Outs {
+		if o == nil {
+			return nil, fmt.Errorf("PSBT output %v is empty", j)
+		}
+	}
+	ut := p.toTx(false)
+	for j, i := range p.Ins {
+		if i.Sig != "" && !i.TXO().IsUnlckd(i.Sig, ut.SigHsh(j)) {
+			return nil, fmt.Errorf("PSBT input %v has an invalid signature", j)
+		}
+	}
+	return p, nil
+}
+
+// Encd (Encode) writes the PSBT as base64 encoded
+// JSON, so that it can be copied to another machine.
+// Returns:
+// string the encoded PSBT
+func (p *PSBT) Encd() string {
+	d, _ := json.Marshal(p)
+	return base64.StdEncoding.EncodeToString(d)
+}
+
+// TXO (TransactionOutput) returns the output that the
+// input spends.
+func (i *PSBTIn) TXO() *txo.TransactionOutput {
+	return &txo.TransactionOutput{Amount: i.Amt, LockingScript: i.LckScrpt}
+}
+
+// Sgn (Sign) signs every input of the PSBT that isn't
+// signed yet and spends an output paying one of the
+// inputted keys.
+// Inputs:
+// get func(string) id.ID returns the key for a
+// locking script, nil if the signer doesn't have it
+// Returns:
+// int the number of inputs signed
+// error if a key could not sign, such as because it
+// is locked
+func (p *PSBT) Sgn(get func(string) id.ID) (int, error) {
+	n := 0
+	ut := p.toTx(false)
+	for j, i := range p.Ins {
+		if i.Sig != "" {
+			continue
+		}
+		k := get(i.LckScrpt)
+		if k == nil {
+			continue
+		}
+		sig, err := i.TXO().MkSig(k, ut.SigHsh(j))
+		if err != nil {
+			return n, fmt.Errorf("could not sign input %v: %v", j, err)
+		}
+		i.Sig = sig
+		n++
+	}
+	return n, nil
+}
+
+// SgnWith (SignWith) signs the inputs of the PSBT
+// that spend outputs paying any of the inputted ids,
+// either to the public key or to its hash (see
+// txo.MkP2PKH), as in Sgn.
+// Inputs:
+// ids ...id.ID the ids to sign with
+// Returns:
+// int the number of inputs signed
+// error if an id could not sign
+func (p *PSBT) SgnWith(ids ...id.ID) (int, error) {
+	return p.Sgn(func(s string) id.ID {
+		for _, i := range ids {
+			pk := i.GetPublicKeyBytes()
+			if s == hex.EncodeToString(pk) || s == txo.MkP2PKH(id.PKHsh(pk)) {
+				return i
+			}
+		}
+		return nil
+	})
+}
+
+// Cmbn (Combine) adds the signatures of other copies
+// of the same PSBT, such as ones signed by different
+// people, to the PSBT.
+// Inputs:
+// ps ...*PSBT the other copies
+// Returns:
+// error if a copy is of a different transaction or
+// has an invalid signature
+func (p *PSBT) Cmbn(ps ...*PSBT) error {
+	ut := p.toTx(false)
+	h := ut.Hash()
+	for _, o := range ps {
+		if o.toTx(false).Hash() != h || len(o.Ins) != len(p.Ins) {
+			return errors.New("PSBTs are of different transactions")
+		}
+		for j, i := range o.Ins {
+			if i.LckScrpt != p.Ins[j].LckScrpt {
+				return fmt.Errorf("PSBT input %v spends an output with a different locking script", j)
+			}
+			if i.Sig == "" || p.Ins[j].Sig != "" {
+				continue
+			}
+			if !i.TXO().IsUnlckd(i.Sig, ut.SigHsh(j)) {
+				return fmt.Errorf("PSBT input %v has an invalid signature", j)
+			}
+			p.Ins[j].Sig = i.Sig
+		}
+	}
+	return nil
+}
+
+// IsCmplt (IsComplete) returns whether every input of
+// the PSBT is signed.
+func (p *PSBT) IsCmplt() bool {
+	for _, i := range p.Ins {
+		if i.Sig == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Fnlz (Finalize) turns the PSBT into the transaction
+// to broadcast, once every input is signed.
+// Returns:
+// *tx.Transaction the signed transaction
+// error if an input isn't signed or a signature is
+// invalid
+func (p *PSBT) Fnlz() (*tx.Transaction, error) {
+	ut := p.toTx(false)
+	for j, i := range p.Ins {
+		if i.Sig == "" {
+			return nil, fmt.Errorf("PSBT input %v is not signed", j)
+		}
+		if !i.TXO().IsUnlckd(i.Sig, ut.SigHsh(j)) {
+			return nil, fmt.Errorf("PSBT input %v has an invalid signature", j)
+		}
+	}
+	return p.toTx(true), nil
+}
+
+// toTx (toTransaction) returns the transaction of
+// the PSBT.
+// Inputs:
+// sgd bool whether to include the signatures
+// Returns:
+// *tx.Transaction the transaction
+func (p *PSBT) toTx(sgd bool) *tx.Transaction {
+	ins := make([]*proto.TransactionInput, len(p.Ins))
+	for j, i := range p.Ins {
+		sig := ""
+		if sgd {
+			sig = i.Sig
+		}
+		ins[j] = proto.NewTxInpt(i.TxHsh, i.OutIdx, sig, i.Amt)
+		ins[j].SequenceNumber = i.Seq
+	}
+	outs := make([]*proto.TransactionOutput, len(p.Outs))
+	for j, o := range p.Outs {
+		outs[j] = proto.NewTxOutpt(o.Amt, o.LckScrpt)
+	}
+	return tx.Deserialize(proto.NewTx(p.Ver, ins, outs, p.LckTm))
+}
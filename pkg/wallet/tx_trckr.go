@@ -0,0 +1,254 @@
+package wallet
+
+import (
+	"BrunoCoin/pkg/block/tx"
+	"sort"
+	"sync"
+)
+
+// Stts (Status) is how far along one of the wallet's
+// transactions is.
+// Pndng (Pending) is a transaction that was made but
+// that the node's pool isn't known to have.
+// InPool is a transaction in the node's pool,
+// waiting to be mined.
+// Cnfrmd (Confirmed) is a transaction on the main
+// chain.
+// Cnflctd (Conflicted) is a transaction that can no
+// longer be mined, since an output it spends was
+// spent by another transaction on the main chain.
+// Drppd (Dropped) is a transaction that left the
+// node's pool without being mined, or that was
+// replaced or never sent by the wallet.
+type Stts int
+
+const (
+	Pndng Stts = iota
+	InPool
+	Cnfrmd
+	Cnflctd
+	Drppd
+)
+
+// String returns the name of the status.
+func (s Stts) String() string {
+	switch s {
+	case Pndng:
+		return "pending"
+	case InPool:
+		return "in pool"
+	case Cnfrmd:
+		return "confirmed"
+	case Cnflctd:
+		return "conflicted"
+	case Drppd:
+		return "dropped"
+	}
+	return "unknown"
+}
+
+// TxStat (TransactionStatus) is the status of one of
+// the wallet's transactions.
+// TxHsh is the hash of the transaction.
+// Stts is the status of the transaction.
+// Hght is the index of the block the transaction is
+// on, -1 unless it is confirmed.
+// Confs are the blocks on the main chain from the one
+// the transaction is on to the last one, 0 unless it
+// is confirmed.
+// Fnl (Final) defines whether the status will no
+// longer change, because the transaction is
+// confirmed SafeBlkAmt deep, or was given up on
+// after it was conflicted or dropped.
+type TxStat struct {
+	TxHsh string
+	Stts  Stts
+	Hght  int
+	Confs int
+	Fnl   bool
+}
+
+// StatFn (StatusFunction) is called with the new
+// status of a transaction whenever it changes.
+type StatFn func(s *TxStat)
+
+// trckd (tracked) is a transaction being tracked.
+// t is the transaction.
+// stat is its last status.
+type trckd struct {
+	t    *tx.Transaction
+	stat TxStat
+}
+
+// TxTrckr (TransactionTracker) follows the status of
+// the wallet's transactions and calls the functions
+// subscribed to them whenever a status changes.
+// txs maps the hash of every tracked transaction to
+// it.
+// subs (subscriptions) maps the hash of a transaction
+// (or "" for every transaction) to its subscribed
+// functions by id.
+// nxtSub (nextSubscription) is the id of the next
+// subscription.
+// ntfy (notify) is held while subscribed functions
+// are called, so that they see changes in order.
+type TxTrckr struct {
+	txs    map[string]*trckd
+	subs   map[string]map[int]StatFn
+	nxtSub int
+	ntfy   sync.Mutex
+	mutex  sync.Mutex
+}
+
+// NewTxTrckr (NewTransactionTracker) returns a
+// tracker that tracks no transactions.
+func NewTxTrckr() *TxTrckr {
+	return &TxTrckr{
+		txs:  make(map[string]*trckd),
+		subs: make(map[string]map[int]StatFn),
+	}
+}
+
+// Add starts tracking a transaction as pending. A
+// transaction that is already tracked is left as
+// it is.
+// Inputs:
+// t *tx.Transaction the transaction
+func (tt *TxTrckr) Add(t *tx.Transaction) {
+	h := t.Hash()
+	tt.mutex.Lock()
+	if _, ok := tt.txs[h]; ok {
+		tt.mutex.Unlock()
+		return
+	}
+	tt.mutex.Unlock()
+	tt.set(t, TxStat{TxHsh: h, Stts: Pndng, Hght: -1})
+}
+
+// Drp (Drop) marks a transaction as dropped for
+// good, such as because it was replaced.
+// Inputs:
+// h string the hash of the transaction
+func (tt *TxTrckr) Drp(h string) {
+	tt.mutex.Lock()
+	tr, ok := tt.txs[h]
+	tt.mutex.Unlock()
+	if ok {
+		tt.set(tr.t, TxStat{TxHsh: h, Stts: Drppd, Hght: -1, Fnl: true})
+	}
+}
+
+// Stat (Status) returns the status of a transaction.
+// Inputs:
+// h string the hash of the transaction
+// Returns:
+// *TxStat the status
+// bool True if the transaction is tracked, false
+// otherwise
+func (tt *TxTrckr) Stat(h string) (*TxStat, bool) {
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+	tr, ok := tt.txs[h]
+	if !ok {
+		return nil, false
+	}
+	s := tr.stat
+	return &s, true
+}
+
+// Sub (Subscribe) calls a function whenever the
+// status of a transaction changes, starting with its
+// current status if it is tracked. The function is
+// called on the goroutine that changed the status,
+// so it must not block or change statuses itself.
+// Inputs:
+// h string the hash of the transaction, or "" for
+// every transaction
+// fn StatFn the function
+// Returns:
+// func() stops calling fn
+func (tt *TxTrckr) Sub(h string, fn StatFn) func() {
+	tt.ntfy.Lock()
+	defer tt.ntfy.Unlock()
+	tt.mutex.Lock()
+	id := tt.nxtSub
+	tt.nxtSub++
+	if tt.subs[h] == nil {
+		tt.subs[h] = make(map[int]StatFn)
+	}
+	tt.subs[h][id] = fn
+	var cur []TxStat
+	for th, tr := range tt.txs {
+		if h == "" || th == h {
+			cur = append(cur, tr.stat)
+		}
+	}
+	tt.mutex.Unlock()
+	sort.Slice(cur, func(i, j int) bool { return cur[i].TxHsh < cur[j].TxHsh })
+	for i := range cur {
+		fn(&cur[i])
+	}
+	return func() {
+		tt.mutex.Lock()
+		delete(tt.subs[h], id)
+		if len(tt.subs[h]) == 0 {
+			delete(tt.subs, h)
+		}
+		tt.mutex.Unlock()
+	}
+}
+
+// Live returns the tracked transactions whose
+// statuses may still change.
+// Returns:
+// []*tx.Transaction the transactions, sorted by hash
+func (tt *TxTrckr) Live() []*tx.Transaction {
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+	var txs []*tx.Transaction
+	for _, tr := range tt.txs {
+		if !tr.stat.Fnl {
+			txs = append(txs, tr.t)
+		}
+	}
+	sort.Slice(txs, func(i, j int) bool { return txs[i].Hash() < txs[j].Hash() })
+	return txs
+}
+
+// set sets the status of a transaction, calling the
+// functions subscribed to it if the status changed.
+// A final status is never changed.
+// Inputs:
+// t *tx.Transaction the transaction
+// s TxStat the new status
+func (tt *TxTrckr) set(t *tx.Transaction, s TxStat) {
+	tt.ntfy.Lock()
+	defer tt.ntfy.Unlock()
+	tt.mutex.Lock()
+	tr, ok := tt.txs[s.TxHsh]
+	if ok && (tr.stat.Fnl || tr.stat == s) {
+		tt.mutex.Unlock()
+		return
+	}
+	if !ok {
+		tr = &trckd{t: t}
+		tt.txs[s.TxHsh] = tr
+	}
+	tr.stat = s
+	var fns []StatFn
+	for _, k := range []string{s.TxHsh, ""} {
+		ids := make([]int, 0, len(tt.subs[k]))
+		for id := range tt.subs[k] {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			fns = append(fns, tt.subs[k][id])
+		}
+	}
+	tt.mutex.Unlock()
+	for _, fn := range fns {
+		c := s
+		fn(&c)
+	}
+}
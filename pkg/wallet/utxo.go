@@ -0,0 +1,190 @@
+package wallet
+
+import (
+	"BrunoCoin/pkg/block"
+	"BrunoCoin/pkg/block/tx/txo"
+	"BrunoCoin/pkg/blockchain"
+	"sort"
+	"sync"
+)
+
+// UTXO is an unspent output on the main chain that
+// the wallet can spend.
+// TxHsh is the hash of the transaction the output is
+// on.
+// OutIdx is the index of the output on the
+// transaction.
+// Amt is the amount of money in the output.
+// LckScrpt is the locking script (public key) that
+// the output pays.
+// Hght is the index of the block the output was made
+// in, -1 for the change of a pending transaction
+// (see Config.SpndUncnfrmd).
+// Cb defines whether the output was made by a
+// coinbase transaction.
+type UTXO struct {
+	TxHsh    string
+	OutIdx   uint32
+	Amt      uint32
+	LckScrpt string
+	Hght     int
+	Cb       bool
+}
+
+// Loc (Locator) returns the locator of the output
+// (see txo.MkTXOLoc).
+func (u *UTXO) Loc() string {
+	return txo.MkTXOLoc(u.TxHsh, u.OutIdx)
+}
+
+// TXO (TransactionOutput) returns the output as it
+// is on the chain, such as to sign it.
+func (u *UTXO) TXO() *txo.TransactionOutput {
+	return &txo.TransactionOutput{Amount: u.Amt, LockingScript: u.LckScrpt}
+}
+
+// UTXOIdx (UTXOIndex) keeps track of the outputs on
+// the main chain that pay the wallet's keys, so that
+// the wallet doesn't have to scan the whole UTXO set
+// to make a transaction, along with the history of
+// the transactions on the main chain that spend or
+// pay them. It is brought up to date with the chain
+// block by block, and rebuilt from the start if the
+// main chain switched to a fork.
+// utxos maps the locator of every output to it.
+// hist (history) are the entries of the wallet's
+// transactions, oldest first.
+// tip is the hash of the last block applied.
+// hght is the index of the last block applied.
+type UTXOIdx struct {
+	utxos map[string]*UTXO
+	hist  []*Entry
+	tip   string
+	hght  int
+	mutex sync.Mutex
+}
+
+// NewUTXOIdx (NewUTXOIndex) returns an empty index.
+func NewUTXOIdx() *UTXOIdx {
+	return &UTXOIdx{utxos: make(map[string]*UTXO), hght: -1}
+}
+
+// Sync brings the index up to date with the main
+// chain.
+// Inputs:
+// bc *blockchain.Blockchain the chain
+// kc *Keychain the keys whose outputs are tracked
+func (ui *UTXOIdx) Sync(bc *blockchain.Blockchain, kc *Keychain) {
+	ui.mutex.Lock()
+	defer ui.mutex.Unlock()
+	blks := bc.List()
+	strt := 0
+	if ui.hght >= 0 && ui.hght < len(blks) && blks[ui.hght].Hash() == ui.tip {
+		strt = ui.hght + 1
+	} else {
+		ui.utxos, ui.hist = make(map[string]*UTXO), nil
+	}
+	for i := strt; i < len(blks); i++ {
+		ui.apply(blks[i], i, kc)
+	}
+	if len(blks) > 0 {
+		ui.hght = len(blks) - 1
+		ui.tip = blks[ui.hght].Hash()
+	}
+}
+
+// Rst (Reset) empties the index, so that it is
+// rebuilt on the next Sync, such as after the keys
+// change.
+func (ui *UTXOIdx) Rst() {
+	ui.mutex.Lock()
+	defer ui.mutex.Unlock()
+	ui.utxos, ui.hist = make(map[string]*UTXO), nil
+	ui.tip, ui.hght = "", -1
+}
+
+// Hght (Height) returns the index of the last block
+// applied, -1 if none were.
+func (ui *UTXOIdx) Hght() int {
+	ui.mutex.Lock()
+	defer ui.mutex.Unlock()
+	return ui.hght
+}
+
+// List returns the outputs in the index, oldest
+// first.
+// Returns:
+// []*UTXO copies of the outputs
+func (ui *UTXOIdx) List() []*UTXO {
+	ui.mutex.Lock()
+	defer ui.mutex.Unlock()
+	us := make([]*UTXO, 0, len(ui.utxos))
+	for _, u := range ui.utxos {
+		c := *u
+		us = append(us, &c)
+	}
+	sort.Slice(us, func(i, j int) bool {
+		if us[i].Hght != us[j].Hght {
+			return us[i].Hght < us[j].Hght
+		}
+		return us[i].Loc() < us[j].Loc()
+	})
+	return us
+}
+
+// Hist (History) returns the entries of the wallet's
+// transactions on the main chain, oldest first.
+// Returns:
+// []*Entry copies of the entries, with their
+// confirmations counted up to the last block applied
+func (ui *UTXOIdx) Hist() []*Entry {
+	ui.mutex.Lock()
+	defer ui.mutex.Unlock()
+	es := make([]*Entry, len(ui.hist))
+	for i, e := range ui.hist {
+		c := *e
+		c.Confs = ui.hght - e.Hght + 1
+		es[i] = &c
+	}
+	return es
+}
+
+// apply removes the outputs spent by a block, adds
+// the outputs it makes for the wallet's keys and
+// records the transactions involving them. Callers
+// must hold ui.mutex.
+// Inputs:
+// b *block.Block the block
+// hght int the index of the block
+// kc *Keychain the keys whose outputs are tracked
+func (ui *UTXOIdx) apply(b *block.Block, hght int, kc *Keychain) {
+	own := func(loc string) (uint32, bool) {
+		if u, ok := ui.utxos[loc]; ok {
+			return u.Amt, true
+		}
+		return 0, false
+	}
+	for _, t := range b.Transactions {
+		if e := mkEnt(t, hght, own, kc); e != nil {
+			ui.hist = append(ui.hist, e)
+		}
+		for _, i := range t.Inputs {
+			delete(ui.utxos, txo.MkTXOLoc(i.TransactionHash, i.OutputIndex))
+		}
+		for j, o := range t.Outputs {
+			if !kc.Has(o.LockingScript) {
+				continue
+			}
+			kc.MrkUsd(o.LockingScript)
+			u := &UTXO{
+				TxHsh:    t.Hash(),
+				OutIdx:   uint32(j),
+				Amt:      o.Amount,
+				LckScrpt: o.LockingScript,
+				Hght:     hght,
+				Cb:       t.IsCoinbase(),
+			}
+			ui.utxos[u.Loc()] = u
+		}
+	}
+}
@@ -3,10 +3,19 @@ package wallet
 import (
 	"BrunoCoin/pkg/block"
 	"BrunoCoin/pkg/block/tx"
+	"BrunoCoin/pkg/block/tx/txo"
 	"BrunoCoin/pkg/blockchain"
+	"BrunoCoin/pkg/fee"
 	"BrunoCoin/pkg/id"
 	"BrunoCoin/pkg/proto"
+	"BrunoCoin/pkg/utils"
+	"context"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"sync"
 )
 
@@ -22,12 +31,143 @@ import (
 // public key of the person they want to pay.
 // Amt (Amount) represents the amount of money
 // they want to pay the person.
+// Fee is the least fee the transaction pays.
+// FeeRt (FeeRate) is the least fee rate the
+// transaction pays. If neither Fee nor FeeRt are set,
+// the fee rate is estimated (see Wallet.EstFeeRt).
+// Addr (Address) is the address of the person to
+// pay, instead of PubK (see id.PKAddr).
+// Pmts (Payments) are more people to pay in the
+// same transaction, each with their own output.
+// PubK and Addr may be empty if there are Pmts.
+// Frm (From) are the locators of the outputs the
+// transaction spends, all of them and no others. If
+// it is empty, the wallet chooses (see Conf.CoinSel).
 type TxReq struct {
+	PubK  []byte
+	Addr  string
+	Amt   uint32
+	Fee   uint32
+	FeeRt fee.Rt
+	Pmts  []*Pmt
+	Frm   []string
+}
+
+// Pmt (Payment) is one person that a transaction
+// pays.
+// PubK (PublicKey) is the serialized public key of
+// the person.
+// Addr (Address) is the address of the person,
+// instead of PubK.
+// Amt (Amount) is the amount of money to pay them.
+type Pmt struct {
 	PubK []byte
+	Addr string
 	Amt  uint32
-	Fee  uint32
 }
 
+// scrpt (script) returns the locking script of the
+// payment's output: the hex encoded public key, or a
+// pay-to-public-key-hash script for an address.
+// Inputs:
+// ver byte the version of the network's addresses
+// Returns:
+// string the locking script
+// error if the payment has no recipient or both
+// kinds, or its public key or address is invalid
+func (p *Pmt) scrpt(ver byte) (string, error) {
+	switch {
+	case p.PubK == nil && p.Addr == "":
+		return "", errors.New("no public key or address")
+	case p.PubK != nil && p.Addr != "":
+		return "", errors.New("both a public key and an address")
+	case p.Addr != "":
+		pkh, err := id.PrsAddr(p.Addr, ver)
+		if err != nil {
+			return "", fmt.Errorf("an invalid address (%v)", err)
+		}
+		return txo.MkP2PKH(pkh), nil
+	}
+	if _, err := utils.Byt2PK(p.PubK); err != nil {
+		return "", fmt.Errorf("an invalid public key (%v)", err)
+	}
+	return hex.EncodeToString(p.PubK), nil
+}
+
+// all returns every payment of the request.
+func (txR *TxReq) all() []*Pmt {
+	if txR.PubK == nil && txR.Addr == "" {
+		return txR.Pmts
+	}
+	return append([]*Pmt{{PubK: txR.PubK, Addr: txR.Addr, Amt: txR.Amt}}, txR.Pmts...)
+}
+
+// ChkPmts (CheckPayments) checks that payments can
+// be made in one transaction: that there is at
+// least one, that every public key or address is
+// valid and paid once, that every amount is
+// positive, and that the total fits in an amount.
+// Inputs:
+// pmts []*Pmt the payments
+// ver byte the version of the network's addresses
+// (see Conf.AddrVer)
+// Returns:
+// uint32 the total amount of the payments
+// error naming the first invalid payment, wrapping
+// ErrInvldRcpnt or ErrInvldAmt
+func ChkPmts(pmts []*Pmt, ver byte) (uint32, error) {
+	if len(pmts) == 0 {
+		return 0, fmt.Errorf("no payments: %w", ErrInvldRcpnt)
+	}
+	var sum uint64
+	seen := make(map[string]bool, len(pmts))
+	for i, p := range pmts {
+		if p == nil {
+			return 0, fmt.Errorf("payment %v has no public key: %w", i, ErrInvldRcpnt)
+		}
+		s, err := p.scrpt(ver)
+		switch {
+		case err != nil:
+			return 0, fmt.Errorf("payment %v has %v: %w", i, err, ErrInvldRcpnt)
+		case p.Amt == 0:
+			return 0, fmt.Errorf("payment %v has a non-positive amount: %w", i, ErrInvldAmt)
+		case seen[s]:
+			return 0, fmt.Errorf("payment %v pays the same recipient as an earlier payment: %w", i, ErrInvldRcpnt)
+		}
+		seen[s] = true
+		sum += uint64(p.Amt)
+	}
+	if sum > math.MaxUint32 {
+		return 0, fmt.Errorf("payments add up to more than the largest amount: %w", ErrInvldAmt)
+	}
+	return uint32(sum), nil
+}
+
+// ErrWtchOnly (ErrorWatchOnly) is returned when a
+// watch-only wallet is asked to do something that
+// needs keys it doesn't have.
+var ErrWtchOnly = errors.New("wallet is watch-only")
+
+// Errors returned (possibly wrapped) when a
+// transaction can't be made, along with ErrInsfFnds
+// and ErrWtchOnly.
+// ErrInvldRcpnt (ErrorInvalidRecipient) is returned
+// when a public key or address to pay is missing,
+// invalid or paid twice.
+// ErrInvldAmt (ErrorInvalidAmount) is returned when an
+// amount to pay is 0 or the amounts add up to too much.
+// ErrFeeTooLow (ErrorFeeTooLow) is returned when the
+// fee rate of the transaction is below MinRlyRt, so
+// that nodes wouldn't relay it.
+// ErrLckd (ErrorLocked) is returned when a key that
+// has to sign is locked (see id.Keystore).
+var (
+	ErrInvldRcpnt = errors.New("invalid recipient")
+	ErrInvldAmt   = errors.New("invalid amount")
+	ErrFeeTooLow  = errors.New("fee rate is below the minimum relay rate")
+	ErrLckd       = id.ErrLckd
+)
+
 // Wallet provides the functionality to make
 // transactions from transaction requests and
 // send them to the node to be broadcast on
@@ -35,10 +175,19 @@ type TxReq struct {
 // Conf represents the configuration for the
 // wallet.
 // Id represents the identity of the person
-// using the wallet.
+// using the wallet. The miner pays this key, so
+// it is part of Keys as well, unless the wallet
+// is watch-only.
+// Keys are the keys the wallet can spend with,
+// derived from a seed so that every transaction
+// can pay a fresh key, and the keys it watches.
+// mnem (mnemonic) are the words the seed of Keys is
+// made from, which back up the wallet.
 // Chain represents the blockchain, as the
 // wallet needs to be able to query the chain
 // for enough UTXO to fulfill a transaction request.
+// UTXOs are the outputs on Chain that pay Keys,
+// which transactions are made from.
 // SendTx (SendTransaction) is a channel for sending
 // fulfilled transaction requests (now in the form of
 // a transaction) to the node, in order to be sent
@@ -47,17 +196,44 @@ type TxReq struct {
 // transactions that the wallet has made, but that
 // do not have enough proof of work on top of them
 // to be considered valid by everyone.
+// FeeEst (FeeEstimator) is used to estimate fees
+// for transactions. It may be nil, in which case
+// fees can't be estimated.
+// MinRlyRt (MinimumRelayRate) is the lowest fee rate
+// the wallet's transactions may pay, so that the
+// node will relay them.
+// Trckr (Tracker) follows the status of the
+// transactions the wallet sent.
+// InPool returns whether the node's pool has a
+// transaction. It may be nil, in which case the
+// wallet's transactions stay pending until mined.
+// frzn (frozen) are the locators of the outputs that
+// the wallet doesn't spend unless they are chosen by
+// hand (see Frz).
+// sndMut (sendMutex) is held from choosing the
+// outputs a transaction spends until they are
+// reserved, so that concurrent sends can't pick the
+// same ones.
 // Mut (Mutex) is a mutex for concurrent accesses
 // to non-atomic reads/writes for the struct
 type Wallet struct {
-	Conf    *Config
-	Id      id.ID
-	Chain   *blockchain.Blockchain
-	SendTx  chan *tx.Transaction
-	LmnlTxs *LiminalTxs
-	Addr    string
+	Conf     *Config
+	Id       id.ID
+	Keys     *Keychain
+	Chain    *blockchain.Blockchain
+	UTXOs    *UTXOIdx
+	SendTx   chan *tx.Transaction
+	LmnlTxs  *LiminalTxs
+	Addr     string
+	FeeEst   *fee.Estimator
+	MinRlyRt fee.Rt
+	Trckr    *TxTrckr
+	InPool   func(h string) bool
 
-	mutex sync.Mutex
+	mnem   string
+	frzn   map[string]bool
+	sndMut sync.Mutex
+	mutex  sync.Mutex
 }
 
 // SetAddr (SetAddress) sets the address
@@ -68,7 +244,10 @@ func (w *Wallet) SetAddr(a string) {
 	w.mutex.Unlock()
 }
 
-// New creates a wallet object.
+// New creates a wallet object with a keychain
+// made from the seed stored in the node's keystore
+// (see ldKeychain), or with an empty watch-only
+// keychain if Conf.WtchOnly is set.
 // Inputs:
 // c *Config the configuration
 // for the wallet
@@ -82,15 +261,501 @@ func New(c *Config, id id.ID, chain *blockchain.Blockchain) *Wallet {
 	if !c.HasWt {
 		return nil
 	}
+	kc, mnem := NewWtchKeychain(), ""
+	if !c.WtchOnly {
+		var err error
+		kc, mnem, err = ldKeychain(c, id)
+		if err != nil {
+			panic(err)
+		}
+		kc.Imprt(id)
+	}
 	return &Wallet{
 		Conf:    c,
 		Id:      id,
+		Keys:    kc,
 		Chain:   chain,
+		UTXOs:   NewUTXOIdx(),
 		SendTx:  make(chan *tx.Transaction),
 		LmnlTxs: NewLmnlTxs(c),
+		Trckr:   NewTxTrckr(),
+		mnem:    mnem,
+		frzn:    make(map[string]bool),
+	}
+}
+
+// ldKeychain (loadKeychain) makes the wallet's
+// keychain out of the seed stored in the node's
+// keystore, so that a restarted node keeps its keys.
+// If the node has no keystore, or the keystore has no
+// seed yet, the keychain is made from a random
+// mnemonic of Conf.MnemBits bits, whose seed is then
+// stored in the keystore, if there is one. A locked
+// keystore can't be read or written, so the keychain
+// then only lives in memory.
+// Inputs:
+// c *Config the configuration for the wallet
+// i id.ID the id of the node, an *id.Keystore if the
+// node's key is stored in a file
+// Returns:
+// *Keychain the keychain
+// string the words of the mnemonic, empty if the
+// seed was stored without one
+// error if the seed could not be read, made or
+// stored
+func ldKeychain(c *Config, i id.ID) (*Keychain, string, error) {
+	ks, _ := i.(*id.Keystore)
+	if ks != nil {
+		seed, mnem, err := ks.Seed()
+		if errors.Is(err, id.ErrLckd) {
+			ks = nil
+		} else if err != nil {
+			return nil, "", err
+		} else if seed != nil {
+			kc, err := NewKeychain(seed, c.GapLim)
+			return kc, mnem, err
+		}
+	}
+	mnem, err := id.NewMnem(c.MnemBits)
+	if err != nil {
+		return nil, "", err
+	}
+	seed, err := id.MnemToSeed(mnem, "")
+	if err != nil {
+		return nil, "", err
+	}
+	kc, err := NewKeychain(seed, c.GapLim)
+	if err != nil {
+		return nil, "", err
+	}
+	if ks != nil {
+		if err := ks.SetSeed(seed, mnem); err != nil {
+			return nil, "", err
+		}
+	}
+	return kc, mnem, nil
+}
+
+// Bckp (Backup) returns the mnemonic that the
+// wallet's keys are derived from. Along with the
+// passphrase (if any), it is all that is needed to
+// restore the wallet (see Rstr).
+// Returns:
+// string the words of the mnemonic, empty if the
+// wallet is watch-only
+func (w *Wallet) Bckp() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.mnem
+}
+
+// Rstr (Restore) replaces the wallet's keys with the
+// ones derived from a backed up mnemonic, and scans
+// the chain for the keys that were used (see
+// Keychain.Dscvr), so that the wallet's coins are
+// found again. If the node's key is stored in an
+// unlocked keystore, the new seed replaces the one
+// stored there, so that the restored keys are kept
+// across restarts.
+// Inputs:
+// mnem string the words of the mnemonic
+// pass string the passphrase, which may be empty
+// Returns:
+// error ErrWtchOnly if the wallet is watch-only, if
+// the mnemonic is invalid, or if the seed could not
+// be stored in the keystore
+func (w *Wallet) Rstr(mnem string, pass string) error {
+	if w.Conf.WtchOnly {
+		return ErrWtchOnly
+	}
+	seed, err := id.MnemToSeed(mnem, pass)
+	if err != nil {
+		return err
+	}
+	nrml := strings.Join(strings.Fields(strings.ToLower(mnem)), " ")
+	// Store the seed first, so that a failure leaves the
+	// wallet with the keys that are stored. The keys of a
+	// locked keystore only live in memory (see ldKeychain)
+	if ks, ok := w.Id.(*id.Keystore); ok {
+		if err := ks.SetSeed(seed, nrml); err != nil && !errors.Is(err, id.ErrLckd) {
+			return err
+		}
+	}
+	if err := w.Keys.SetSeed(seed); err != nil {
+		return err
+	}
+	w.Keys.Dscvr(w.Chain)
+	w.UTXOs.Rst()
+	w.mutex.Lock()
+	w.mnem = nrml
+	w.mutex.Unlock()
+	utils.Debug.Printf("%v restored wallet, balance %v", utils.FmtAddr(w.Addr), w.Bal())
+	return nil
+}
+
+// NewRcvPK (NewReceivePublicKey) returns a public
+// key of the wallet that hasn't been handed out
+// before, for someone to pay.
+// Returns:
+// []byte the serialized public key
+// error ErrWtchOnly if the wallet is watch-only
+func (w *Wallet) NewRcvPK() ([]byte, error) {
+	k := w.Keys.NewKey(Rcv)
+	if k == nil {
+		return nil, ErrWtchOnly
+	}
+	return k.GetPublicKeyBytes(), nil
+}
+
+// NewRcvAddr (NewReceiveAddress) returns the address
+// of a public key of the wallet that hasn't been
+// handed out before (see NewRcvPK). Payments to it
+// have a pay-to-public-key-hash locking script.
+// Returns:
+// string the address
+// error ErrWtchOnly if the wallet is watch-only
+func (w *Wallet) NewRcvAddr() (string, error) {
+	pk, err := w.NewRcvPK()
+	if err != nil {
+		return "", err
+	}
+	return id.PKAddr(w.Conf.AddrVer, pk), nil
+}
+
+// WtchPK (WatchPublicKey) adds a public key for the
+// wallet to watch: its outputs and history on the
+// chain are tracked, and unsigned transactions can
+// spend them (see MkUnsgndTx), but the wallet can't
+// sign for it.
+// Inputs:
+// pk []byte the serialized public key
+// Returns:
+// error if the public key is invalid
+func (w *Wallet) WtchPK(pk []byte) error {
+	return w.WtchScrpt(hex.EncodeToString(pk))
+}
+
+// WtchScrpt (WatchScript) adds a locking script for
+// the wallet to watch (see WtchPK). The history of
+// the wallet is rebuilt from the chain, so that
+// outputs the script was paid before are found.
+// Inputs:
+// s string the locking script, a hex encoded public
+// key or a pay-to-public-key-hash script
+// Returns:
+// error if the locking script is invalid
+func (w *Wallet) WtchScrpt(s string) error {
+	if _, ok := txo.PrsP2PKH(s); !ok {
+		pk, err := hex.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("invalid locking script: %v", err)
+		}
+		if _, err := utils.Byt2PK(pk); err != nil {
+			return fmt.Errorf("invalid locking script: %v", err)
+		}
+	}
+	if !w.Keys.Has(s) {
+		w.Keys.Wtch(s)
+		w.UTXOs.Rst()
+	}
+	return nil
+}
+
+// Bal (Balance) returns how much money the wallet
+// has on the main chain, across all of its keys.
+// Returns:
+// uint32 the balance
+func (w *Wallet) Bal() uint32 {
+	var bal uint32
+	for _, u := range w.Coins() {
+		bal += u.Amt
+	}
+	return bal
+}
+
+// Coins returns the outputs on the main chain that
+// pay the wallet, oldest first.
+// Returns:
+// []*UTXO the outputs
+func (w *Wallet) Coins() []*UTXO {
+	w.UTXOs.Sync(w.Chain, w.Keys)
+	return w.UTXOs.List()
+}
+
+// spndbl (spendable) returns the outputs that the
+// wallet's transactions may spend (see Coin.Spndbl).
+// Inputs:
+// sgnd bool whether the outputs must be signed for
+// by the wallet, leaving out ones paying watched keys
+// Returns:
+// []*UTXO the outputs
+func (w *Wallet) spndbl(sgnd bool) []*UTXO {
+	cs := w.ListCoins()
+	us := make([]*UTXO, 0, len(cs))
+	for _, c := range cs {
+		if w.cnSpnd(c, sgnd) {
+			us = append(us, c.UTXO)
+		}
+	}
+	return us
+}
+
+// spntBy (spentBy) returns the locators of the
+// outputs that transactions spend.
+// Inputs:
+// txs []*tx.Transaction the transactions
+// Returns:
+// map[string]bool the locators of the outputs spent
+func spntBy(txs []*tx.Transaction) map[string]bool {
+	spnt := make(map[string]bool)
+	for _, t := range txs {
+		for _, i := range t.Inputs {
+			spnt[txo.MkTXOLoc(i.TransactionHash, i.OutputIndex)] = true
+		}
+	}
+	return spnt
+}
+
+// immtr (immature) returns whether an output is
+// from a coinbase transaction with fewer than
+// Conf.CbMtr confirmations. The genesis block
+// isn't mined, so its outputs are always mature.
+// Inputs:
+// u *UTXO the output
+// tip int the index of the last block on the
+// main chain
+func (w *Wallet) immtr(u *UTXO, tip int) bool {
+	return u.Cb && u.Hght > 0 && tip-u.Hght+1 < w.Conf.CbMtr
+}
+
+// Hist (History) returns the wallet's transactions:
+// every transaction on the main chain that spends or
+// pays its outputs, oldest first, followed by the
+// ones it made that are still pending.
+// Returns:
+// []*Entry the wallet's transactions
+func (w *Wallet) Hist() []*Entry {
+	w.UTXOs.Sync(w.Chain, w.Keys)
+	hist := w.UTXOs.Hist()
+	own := make(map[string]uint32)
+	for _, u := range w.UTXOs.List() {
+		own[u.Loc()] = u.Amt
+	}
+	pndng := w.pndng(hist)
+	// Pending transactions may spend the change of other
+	// pending transactions
+	for _, t := range pndng {
+		for j, o := range t.Outputs {
+			if w.Keys.Has(o.LockingScript) {
+				own[txo.MkTXOLoc(t.Hash(), uint32(j))] = o.Amount
+			}
+		}
+	}
+	ownFn := func(loc string) (uint32, bool) {
+		a, ok := own[loc]
+		return a, ok
+	}
+	for _, t := range pndng {
+		if e := mkEnt(t, -1, ownFn, w.Keys); e != nil {
+			hist = append(hist, e)
+		}
+	}
+	return hist
+}
+
+// Bals (Balances) returns the wallet's money, split
+// up by whether it can be spent (see Bals).
+// Returns:
+// *Bals the balances
+func (w *Wallet) Bals() *Bals {
+	w.UTXOs.Sync(w.Chain, w.Keys)
+	bs := &Bals{}
+	pndng := w.pndng(w.UTXOs.Hist())
+	spnt := spntBy(pndng)
+	for _, t := range pndng {
+		for j, o := range t.Outputs {
+			// Change spent by another pending transaction
+			// is counted there instead
+			if w.Keys.Has(o.LockingScript) && !spnt[txo.MkTXOLoc(t.Hash(), uint32(j))] {
+				bs.Uncnfrmd += o.Amount
+			}
+		}
+	}
+	tip := w.UTXOs.Hght()
+	for _, u := range w.UTXOs.List() {
+		switch {
+		case spnt[u.Loc()]:
+			bs.Lckd += u.Amt
+		case w.immtr(u, tip):
+			bs.Immtr += u.Amt
+		default:
+			bs.Cnfrmd += u.Amt
+		}
+	}
+	return bs
+}
+
+// pndng (pending) returns the wallet's liminal
+// transactions that aren't on the main chain yet,
+// sorted by hash.
+// Inputs:
+// hist []*Entry the wallet's transactions on the
+// main chain
+// Returns:
+// []*tx.Transaction the pending transactions
+func (w *Wallet) pndng(hist []*Entry) []*tx.Transaction {
+	onChn := make(map[string]bool, len(hist))
+	for _, e := range hist {
+		onChn[e.TxHsh] = true
+	}
+	var txs []*tx.Transaction
+	for _, t := range w.LmnlTxs.List() {
+		if !onChn[t.Hash()] {
+			txs = append(txs, t)
+		}
+	}
+	sort.Slice(txs, func(i, j int) bool { return txs[i].Hash() < txs[j].Hash() })
+	return txs
+}
+
+// EstFee (EstimateFee) estimates the fee that a
+// transaction of the inputted size needs to pay in
+// order to be mined within Conf.FeeTrgt blocks.
+// Inputs:
+// sz uint32 the size of the transaction in bytes
+// Returns:
+// uint32 the estimated fee
+// bool True if there was enough data to estimate the
+// fee, false otherwise
+func (w *Wallet) EstFee(sz uint32) (uint32, bool) {
+	rt, ok := w.EstFeeRt()
+	if !ok {
+		return 0, false
+	}
+	return uint32(rt.Fee(sz)), true
+}
+
+// EstFeeRt (EstimateFeeRate) estimates the fee rate
+// that a transaction needs to pay in order to be
+// mined within Conf.FeeTrgt blocks.
+// Returns:
+// fee.Rt the estimated fee rate
+// bool True if there was enough data to estimate the
+// fee rate, false otherwise
+func (w *Wallet) EstFeeRt() (fee.Rt, bool) {
+	if w.FeeEst == nil {
+		return 0, false
+	}
+	return w.FeeEst.EstFeeRt(w.Conf.FeeTrgt)
+}
+
+// feeFn (feeFunction) returns the fee that a
+// transaction made from a request pays for its
+// number of inputs and outputs: the larger of the
+// requested fee and the requested fee rate. If
+// neither were requested, the estimated fee rate is
+// paid, or Conf.FallbkRt without an estimate, but
+// never less than MinRlyRt.
+// Inputs:
+// txR *TxReq the transaction request
+// Returns:
+// FeeFn the fee for a number of inputs and outputs
+func (w *Wallet) feeFn(txR *TxReq) FeeFn {
+	rt := txR.FeeRt
+	if txR.Fee == 0 && rt == 0 {
+		est, ok := w.EstFeeRt()
+		if !ok {
+			est = w.Conf.FallbkRt
+		}
+		rt = est
+		if rt < w.MinRlyRt {
+			rt = w.MinRlyRt
+		}
+	}
+	return func(nIns int, nOuts int) uint32 {
+		f := rt.Fee(TxSz(nIns, nOuts))
+		if f < uint64(txR.Fee) {
+			f = uint64(txR.Fee)
+		}
+		if f > math.MaxUint32 {
+			f = math.MaxUint32
+		}
+		return uint32(f)
 	}
 }
 
+// UpdtStats (UpdateStatuses) brings the statuses of
+// the wallet's transactions up to date with the main
+// chain and the node's pool, calling the functions
+// subscribed to any that changed. It is called
+// whenever either of them changes.
+func (w *Wallet) UpdtStats() {
+	if w == nil {
+		return
+	}
+	w.UTXOs.Sync(w.Chain, w.Keys)
+	tip := w.UTXOs.Hght()
+	onChn := make(map[string]int)
+	for _, e := range w.UTXOs.Hist() {
+		onChn[e.TxHsh] = e.Hght
+	}
+	unspnt := make(map[string]bool)
+	for _, u := range w.UTXOs.List() {
+		unspnt[u.Loc()] = true
+	}
+	for _, t := range w.Trckr.Live() {
+		h := t.Hash()
+		s := TxStat{TxHsh: h, Stts: Pndng, Hght: -1}
+		prv, _ := w.Trckr.Stat(h)
+		if hght, ok := onChn[h]; ok {
+			s.Stts, s.Hght, s.Confs = Cnfrmd, hght, tip-hght+1
+			s.Fnl = s.Confs >= w.Conf.SafeBlkAmt
+		} else if w.cnflctd(t, onChn, unspnt) {
+			s.Stts = Cnflctd
+		} else if w.InPool != nil && w.InPool(h) {
+			s.Stts = InPool
+		} else if prv.Stts != Pndng {
+			// It was in the pool or on the chain, but has left
+			s.Stts = Drppd
+		}
+		// Neither will be mined as they are, so the outputs
+		// they spend are released and they aren't sent again
+		if s.Stts == Cnflctd || s.Stts == Drppd {
+			s.Fnl = true
+			w.LmnlTxs.Rmv(t)
+		}
+		w.Trckr.set(t, s)
+	}
+}
+
+// cnflctd (conflicted) returns whether a transaction
+// spends an output that another transaction on the
+// main chain spent, or that a conflicted or dropped
+// transaction made.
+// Inputs:
+// t *tx.Transaction the transaction, not on the main
+// chain
+// onChn map[string]int the wallet's transactions on
+// the main chain
+// unspnt map[string]bool the locators of the wallet's
+// unspent outputs on the main chain
+// Returns:
+// bool True if t can no longer be mined
+func (w *Wallet) cnflctd(t *tx.Transaction, onChn map[string]int, unspnt map[string]bool) bool {
+	for _, i := range t.Inputs {
+		if unspnt[txo.MkTXOLoc(i.TransactionHash, i.OutputIndex)] {
+			continue
+		}
+		if _, ok := onChn[i.TransactionHash]; ok {
+			return true
+		}
+		if s, ok := w.Trckr.Stat(i.TransactionHash); ok && (s.Stts == Cnflctd || s.Stts == Drppd) {
+			return true
+		}
+	}
+	return false
+}
+
 // HndlBlk (HandleBlock) is called after a new
 // block is added to the main chain. However, the
 // inputted block is a "safe block amount" down from
@@ -125,10 +790,25 @@ func (w *Wallet) HndlBlk(b *block.Block) {
 	if w == nil || b == nil {
 		return
 	}
-	_, oldTransactions := w.LmnlTxs.ChkTxs(b.Transactions)
+	for _, t := range b.Transactions {
+		for _, o := range t.Outputs {
+			w.Keys.MrkUsd(o.LockingScript)
+		}
+	}
+	// Only the old transactions are sent again, and they
+	// stay liminal throughout, since releasing the outputs
+	// they spend while they may still be mined would let
+	// them be spent twice
+	oldTransactions := w.LmnlTxs.Rply(b.Transactions)
+	w.UTXOs.Sync(w.Chain, w.Keys)
+	onChn := make(map[string]bool)
+	for _, e := range w.UTXOs.Hist() {
+		onChn[e.TxHsh] = true
+	}
 	for _, trans := range oldTransactions {
-		if trans != nil {
-			w.LmnlTxs.Add(trans)
+		// Ones already on the main chain only need more
+		// blocks on top of them
+		if trans != nil && !onChn[trans.Hash()] {
 			w.SendTx <- trans
 		}
 	}
@@ -172,7 +852,7 @@ func (w *Wallet) HndlBlk(b *block.Block) {
 // let t be a transaction object
 // w.Id.GetPublicKeyBytes()
 // hex.EncodeToString(...)
-// w.Chain.GetUTXOForAmt(...)
+// SlctCoins(...)
 // proto.NewTx(...)
 // tx.Deserialize(...)
 // w.LmnlTxs.Add(...)
@@ -183,41 +863,356 @@ func (w *Wallet) HndlBlk(b *block.Block) {
 // proto.NewTxInpt(...)
 // proto.NewTxOutpt(...)
 func (w *Wallet) HndlTxReq(txR *TxReq) {
-	if txR == nil || txR.Amt == 0 {
-		return
+	if _, err := w.Snd(context.Background(), txR); err != nil {
+		utils.Debug.Printf("%v could not make a transaction: %v", utils.FmtAddr(w.Addr), err)
+	}
+}
+
+// Snd (Send) makes a transaction from a request and
+// sends it to the node to be broadcast, waiting until
+// the node takes it.
+// Inputs:
+// ctx context.Context cancels waiting for the node
+// txR *TxReq the transaction request
+// Returns:
+// *tx.Transaction the transaction that was sent
+// error ErrInsfFnds, ErrInvldRcpnt, ErrInvldAmt,
+// ErrFeeTooLow, ErrLckd or ErrWtchOnly (possibly
+// wrapped, see errors.Is) if the transaction could
+// not be made, or the error of ctx if it was
+// cancelled before the node took the transaction
+func (w *Wallet) Snd(ctx context.Context, txR *TxReq) (*tx.Transaction, error) {
+	if txR == nil {
+		return nil, errors.New("no transaction request")
+	}
+	// 1. - 4. Make the transaction from enough UTXO
+	// (see mkTx)
+	return w.snd(ctx, nil, func() (*tx.Transaction, error) { return w.mkTx(txR, true) })
+}
+
+// snd (send) makes a transaction and sends it to the
+// node to be broadcast (see Snd).
+// Inputs:
+// ctx context.Context cancels waiting for the node
+// old *tx.Transaction the liminal transaction that the
+// new one replaces (see BumpFee), nil if there is
+// none
+// mk func() (*tx.Transaction, error) makes the
+// signed transaction
+// Returns:
+// *tx.Transaction the transaction that was sent
+// error if the transaction could not be made or ctx
+// was cancelled
+func (w *Wallet) snd(ctx context.Context, old *tx.Transaction, mk func() (*tx.Transaction, error)) (*tx.Transaction, error) {
+	if w.Conf.WtchOnly {
+		return nil, ErrWtchOnly
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	w.sndMut.Lock()
+	t, err := mk()
+	if err != nil {
+		w.sndMut.Unlock()
+		return nil, err
+	}
+	// 5. Add the transaction to liminal transactions, which
+	// reserves the outputs it spends, and track it before
+	// it is sent so that no status change is missed
+	w.Trckr.Add(t)
+	if old != nil {
+		w.LmnlTxs.Rplc(old, t)
+	} else {
+		w.LmnlTxs.Add(t)
+	}
+	w.sndMut.Unlock()
+	// 6. Send the transaction to the node to be broadcast,
+	// releasing its outputs (or putting back the one it
+	// replaces) if it never is
+	select {
+	case w.SendTx <- t:
+	case <-ctx.Done():
+		if old != nil {
+			w.LmnlTxs.Rplc(t, old)
+		} else {
+			w.LmnlTxs.Rmv(t)
+		}
+		w.Trckr.Drp(t.Hash())
+		return nil, ctx.Err()
+	}
+	if old != nil {
+		w.Trckr.Drp(old.Hash())
+	}
+	utils.Debug.Printf("%v made %v", utils.FmtAddr(w.Addr), t.NameTag())
+	return t, nil
+}
+
+// MkUnsgndTx (MakeUnsignedTransaction) makes a
+// transaction from a request the same way as
+// HndlTxReq, but without signing or sending it, so
+// that it can be signed somewhere that has the keys.
+// Outputs paying watched keys may be spent, and the
+// change of a watch-only wallet is paid back to the
+// key of the first output spent.
+// Inputs:
+// txR *TxReq the transaction request
+// Returns:
+// *tx.Transaction the transaction, with empty
+// unlocking scripts
+// error if the request is invalid or can't be paid
+func (w *Wallet) MkUnsgndTx(txR *TxReq) (*tx.Transaction, error) {
+	if txR == nil {
+		return nil, errors.New("no transaction request")
+	}
+	return w.mkTx(txR, false)
+}
+
+// MkPSBT (MakePSBT) makes an unsigned transaction
+// from a request (see MkUnsgndTx) as a PSBT, along
+// with the outputs it spends, so that it can be
+// signed on another machine.
+// Inputs:
+// txR *TxReq the transaction request
+// Returns:
+// *PSBT the PSBT
+// error if the request is invalid or can't be paid
+func (w *Wallet) MkPSBT(txR *TxReq) (*PSBT, error) {
+	t, err := w.MkUnsgndTx(txR)
+	if err != nil {
+		return nil, err
+	}
+	cs := make(map[string]*UTXO)
+	for _, c := range w.ListCoins() {
+		cs[c.Loc()] = c.UTXO
+	}
+	spnt := make([]*txo.TransactionOutput, len(t.Inputs))
+	for j, i := range t.Inputs {
+		if u, ok := cs[txo.MkTXOLoc(i.TransactionHash, i.OutputIndex)]; ok {
+			spnt[j] = u.TXO()
+		}
+	}
+	return NewPSBT(t, spnt)
+}
+
+// SgnPSBT (SignPSBT) signs the inputs of a PSBT that
+// spend the wallet's outputs.
+// Inputs:
+// p *PSBT the PSBT
+// Returns:
+// int the number of inputs signed
+// error ErrWtchOnly if the wallet is watch-only, or
+// if a key could not sign
+func (w *Wallet) SgnPSBT(p *PSBT) (int, error) {
+	if w.Conf.WtchOnly {
+		return 0, ErrWtchOnly
+	}
+	return p.Sgn(w.Keys.Get)
+}
+
+// TrckPSBT (TrackPSBT) adds the transaction of a
+// finalized PSBT to the wallet's liminal transactions
+// if it spends any of the wallet's outputs, so that
+// the wallet follows it like one it made itself.
+// Inputs:
+// p *PSBT the PSBT
+// t *tx.Transaction the finalized transaction
+func (w *Wallet) TrckPSBT(p *PSBT, t *tx.Transaction) {
+	for _, i := range p.Ins {
+		if w.Keys.Has(i.LckScrpt) {
+			w.Trckr.Add(t)
+			w.LmnlTxs.Add(t)
+			return
+		}
+	}
+}
+
+// mkTx (makeTransaction) makes a transaction from a
+// request.
+// Inputs:
+// txR *TxReq the transaction request
+// sgn bool whether to sign the inputs, spending only
+// outputs the wallet can sign for
+// Returns:
+// *tx.Transaction the transaction
+// error if the request is invalid, can't be paid,
+// pays too low a fee or an input could not be signed
+func (w *Wallet) mkTx(txR *TxReq, sgn bool) (*tx.Transaction, error) {
+	pmts := txR.all()
+	amt, err := ChkPmts(pmts, w.Conf.AddrVer)
+	if err != nil {
+		return nil, err
+	}
+	// 1. Try and find enough UTXO to make the transaction,
+	// including the fee, unless the request chose them
+	var sel *CoinSel
+	if len(txR.Frm) > 0 {
+		var us []*UTXO
+		if us, err = pick(w.spndbl(sgn), txR.Frm); err == nil {
+			sel, err = SpndAll(us, amt, len(pmts), w.feeFn(txR), w.Conf.DustLim)
+		}
+	} else {
+		sel, err = SlctCoins(w.spndbl(sgn), amt, len(pmts), w.feeFn(txR), w.Conf.DustLim, w.Conf.CoinSel)
 	}
-	// 1. Try and find enough UTXO to make the transaction
-	publicKey := hex.EncodeToString(w.Id.GetPublicKeyBytes())
-	utxoForTransaction, change, weHaveEnough := w.Chain.GetUTXOForAmt(txR.Amt, publicKey)
 	// 2. If not enough, return
-	if !weHaveEnough {
-		return
+	if err != nil {
+		return nil, fmt.Errorf("could not pay %v: %w", amt, err)
 	}
+	return w.bldTx(pmts, sel, sgn)
+}
+
+// bldTx (buildTransaction) makes the transaction
+// spending a selection of outputs.
+// Inputs:
+// pmts []*Pmt the payments
+// sel *CoinSel the outputs to spend, the fee and the
+// change
+// sgn bool whether to sign the inputs
+// Returns:
+// *tx.Transaction the transaction
+// error if it pays too low a fee or an input could
+// not be signed
+func (w *Wallet) bldTx(pmts []*Pmt, sel *CoinSel, sgn bool) (*tx.Transaction, error) {
 	// 3. Make the transaction inputs for the transaction
-	// from the UTXO
+	// from the UTXO, signed once the whole transaction is
+	// made, since the signatures cover all of it
 	txInputs := []*proto.TransactionInput{}
-	for _, info := range utxoForTransaction {
-		newInput := proto.NewTxInpt(info.TxHsh, info.OutIdx, publicKey, info.Amt)
+	for _, u := range sel.UTXOs {
+		newInput := proto.NewTxInpt(u.TxHsh, u.OutIdx, "", u.Amt)
+		if w.Conf.RBF {
+			newInput.SequenceNumber = proto.RBFSeq
+		}
 		txInputs = append(txInputs, newInput)
 	}
-	// newTransactionInputs := proto.NewTxInpt(publicKey, ..., w.Addr, ...)
 	// 4. Make the transaction outputs based on who you
 	// send money to and if there is change leftover for
-	// yourself
-	recipientPublicKey := hex.EncodeToString(txR.PubK)
+	// yourself, with a fresh change key
 	txOutputs := []*proto.TransactionOutput{}
-	paymentToRecipient := proto.NewTxOutpt(txR.Amt, recipientPublicKey)
-	txOutputs = append(txOutputs, paymentToRecipient)
-	if change > 0 {
-		changeTransaction := proto.NewTxOutpt(change, publicKey)
-		txOutputs = append(txOutputs, changeTransaction)
-	}
-	newTrans := proto.NewTx(w.Conf.TxVer, txInputs, txOutputs, w.Conf.DefLckTm)
-
-	// 5. Add the transaction to liminal transactions
-	deserializedTx := tx.Deserialize(newTrans)
-	w.LmnlTxs.Add(deserializedTx)
-	// 6. Send the transaction to the node to be broadcast
-	w.SendTx <- deserializedTx
-	return
+	for _, p := range pmts {
+		s, err := p.scrpt(w.Conf.AddrVer)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", err, ErrInvldRcpnt)
+		}
+		txOutputs = append(txOutputs, proto.NewTxOutpt(p.Amt, s))
+	}
+	if sel.Chng > 0 {
+		chngScrpt := sel.UTXOs[0].LckScrpt
+		if k := w.Keys.NewKey(Chng); k != nil {
+			chngScrpt = hex.EncodeToString(k.GetPublicKeyBytes())
+		}
+		txOutputs = append(txOutputs, proto.NewTxOutpt(sel.Chng, chngScrpt))
+	}
+	t := tx.Deserialize(proto.NewTx(w.Conf.TxVer, txInputs, txOutputs, w.Conf.DefLckTm))
+	if rt := fee.FeeRt(t); rt < w.MinRlyRt {
+		return nil, fmt.Errorf("paying %v: %w", rt, ErrFeeTooLow)
+	}
+	if sgn {
+		for j, u := range sel.UTXOs {
+			sig, err := u.TXO().MkSig(w.Keys.Get(u.LckScrpt), t.SigHsh(j))
+			if err != nil {
+				return nil, fmt.Errorf("could not sign %v: %w", u.Loc(), err)
+			}
+			t.Inputs[j].UnlockingScript = sig
+		}
+	}
+	return t, nil
+}
+
+// BumpFee replaces one of the wallet's liminal
+// transactions with a copy paying a higher fee, so
+// that a transaction stuck in the miners' pools can
+// get mined. The copy spends the same UTXO and makes
+// the same payments, with the extra fee taken out of
+// the change, which is left out if it falls below
+// Conf.DustLim. It is signed again and sent like Snd
+// sends, so miners swap it for the original
+// (replace-by-fee).
+// Inputs:
+// ctx context.Context cancels waiting for the node
+// h string the hash of the liminal transaction
+// fee uint32 the new fee, which must be higher than
+// the old one
+// Returns:
+// *tx.Transaction the replacement transaction
+// error if the transaction isn't liminal, doesn't
+// signal replace-by-fee or has its change spent,
+// ErrInsfFnds (wrapped) if there isn't enough change
+// to pay the higher fee, or an error of Snd if the
+// replacement could not be made or sent
+func (w *Wallet) BumpFee(ctx context.Context, h string, fee uint32) (*tx.Transaction, error) {
+	old := w.LmnlTxs.Get(h)
+	if old == nil {
+		return nil, errors.New("transaction is not liminal")
+	}
+	if !old.SignalsRBF() {
+		return nil, errors.New("transaction does not signal replace-by-fee")
+	}
+	if fee <= old.Fee() {
+		return nil, errors.New("new fee must be higher than the old fee")
+	}
+	t, err := w.snd(ctx, old, func() (*tx.Transaction, error) {
+		// Replacing it would leave transactions spending its
+		// change spending nothing
+		for _, t := range w.LmnlTxs.List() {
+			for _, i := range t.Inputs {
+				if i.TransactionHash == h {
+					return nil, errors.New("transaction has liminal transactions spending its change")
+				}
+			}
+		}
+		cs := make(map[string]*UTXO)
+		for _, c := range w.ListCoins() {
+			cs[c.Loc()] = c.UTXO
+		}
+		us := make([]*UTXO, 0, len(old.Inputs))
+		for _, i := range old.Inputs {
+			u, ok := cs[txo.MkTXOLoc(i.TransactionHash, i.OutputIndex)]
+			if !ok {
+				return nil, errors.New("transaction spends an output the wallet no longer has")
+			}
+			us = append(us, u)
+		}
+		var pmts []*Pmt
+		var amt uint32
+		for _, o := range old.Outputs {
+			if w.Keys.IsChng(o.LockingScript) {
+				continue
+			}
+			p, err := w.pmtTo(o)
+			if err != nil {
+				return nil, err
+			}
+			pmts = append(pmts, p)
+			amt += o.Amount
+		}
+		sel, err := SpndAll(us, amt, len(pmts), func(int, int) uint32 { return fee }, w.Conf.DustLim)
+		if err != nil || (len(pmts) == 0 && sel.Chng == 0) {
+			return nil, fmt.Errorf("not enough change to pay the higher fee: %w", ErrInsfFnds)
+		}
+		return w.bldTx(pmts, sel, true)
+	})
+	if err != nil {
+		return nil, err
+	}
+	utils.Debug.Printf("%v bumped fee of %v to %v with %v", utils.FmtAddr(w.Addr), old.NameTag(), t.Fee(), t.NameTag())
+	return t, nil
+}
+
+// pmtTo (paymentTo) returns the payment that made
+// one of the outputs of a transaction, so that the
+// output can be made again.
+// Inputs:
+// o *txo.TransactionOutput the output
+// Returns:
+// *Pmt the payment
+// error if the locking script of the output isn't a
+// public key or a pay-to-public-key-hash script
+func (w *Wallet) pmtTo(o *txo.TransactionOutput) (*Pmt, error) {
+	if pkh, ok := txo.PrsP2PKH(o.LockingScript); ok {
+		return &Pmt{Addr: id.EncdAddr(w.Conf.AddrVer, pkh), Amt: o.Amount}, nil
+	}
+	pk, err := hex.DecodeString(o.LockingScript)
+	if err != nil {
+		return nil, fmt.Errorf("output pays an invalid locking script: %v", err)
+	}
+	return &Pmt{PubK: pk, Amt: o.Amount}, nil
 }
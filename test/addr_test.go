@@ -0,0 +1,141 @@
+package test
+
+import (
+	"BrunoCoin/pkg/block/tx/txo"
+	"BrunoCoin/pkg/id"
+	"BrunoCoin/pkg/wallet"
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestAddr checks that addresses decode back to the
+// hash they were made from, and that mistyped
+// addresses and ones of another network are
+// rejected.
+func TestAddr(t *testing.T) {
+	i, _ := id.CreateSimpleID()
+	pkh := id.PKHsh(i.GetPublicKeyBytes())
+	a := id.PKAddr(id.MainAddrVer, i.GetPublicKeyBytes())
+	if ver, h, err := id.DecdAddr(a); err != nil || ver != id.MainAddrVer || string(h) != string(pkh) {
+		t.Fatalf("Failed: expected %v to decode to its version and hash, got %v %x %v", a, ver, h, err)
+	}
+	if a2 := id.EncdAddr(0, make([]byte, id.PKHshLen)); !strings.HasPrefix(a2, "1") {
+		t.Errorf("Failed: expected a leading zero byte to encode as 1, got %v", a2)
+	}
+	if _, _, err := id.DecdAddr(id.EncdAddr(0, make([]byte, id.PKHshLen))); err != nil {
+		t.Errorf("Failed: could not decode an address with leading zeros: %v", err)
+	}
+
+	typo := []byte(a)
+	if typo[5] == 'a' {
+		typo[5] = 'b'
+	} else {
+		typo[5] = 'a'
+	}
+	for _, bad := range []string{"", string(typo), a[:len(a)-1], a + "1", "0" + a[1:]} {
+		if _, _, err := id.DecdAddr(bad); !errors.Is(err, id.ErrInvldAddr) {
+			t.Errorf("Failed: expected %q to be invalid, got %v", bad, err)
+		}
+	}
+	if _, err := id.PrsAddr(a, id.RegtestAddrVer); !errors.Is(err, id.ErrWrngNet) {
+		t.Errorf("Failed: expected a main network address to be rejected in regtest mode, got %v", err)
+	}
+	if h, err := id.PrsAddr(a, id.MainAddrVer); err != nil || string(h) != string(pkh) {
+		t.Errorf("Failed: could not parse %v: %v", a, err)
+	}
+}
+
+// TestSendToAddr checks that a payment to an address
+// locks its output to the hash of the public key, and
+// that the wallet can spend that output once it is
+// mined.
+func TestSendToAddr(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	genNd.Start()
+	defer genNd.Kill()
+	to, _ := id.CreateSimpleID()
+
+	a, err := genNd.NewRcvAddr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkh, err := id.PrsAddr(a, id.RegtestAddrVer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{a[:len(a)-1] + "1", id.EncdAddr(id.MainAddrVer, pkh)} {
+		if _, err := genNd.SendToAddr(context.Background(), 100, 50, bad); !errors.Is(err, wallet.ErrInvldRcpnt) {
+			t.Errorf("Failed: expected paying %v to fail, got %v", bad, err)
+		}
+	}
+	if _, err := genNd.SendTxReq(context.Background(), &wallet.TxReq{PubK: to.GetPublicKeyBytes(), Addr: a, Amt: 100, Fee: 50}); !errors.Is(err, wallet.ErrInvldRcpnt) {
+		t.Errorf("Failed: expected a payment to both a public key and an address to fail, got %v", err)
+	}
+
+	h, err := genNd.SendToAddr(context.Background(), 100, 50, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := genNd.Wallet.LmnlTxs.Get(h).Outputs[0]; o.LockingScript != txo.MkP2PKH(pkh) {
+		t.Errorf("Failed: expected the payment to pay the hash of the public key, got %v", o.LockingScript)
+	}
+	if !WaitFor(func() bool { return genNd.Mnr.TxP.Ct.Load() == 1 }) {
+		t.Fatalf("Failed: payment did not reach the pool")
+	}
+	if _, err := genNd.GenerateBlocks(1, nil); err != nil {
+		t.Fatal(err)
+	}
+	loc := txo.MkTXOLoc(h, 0)
+	fnd := false
+	cs, _ := genNd.WtCoins()
+	for _, c := range cs {
+		if c.Loc() == loc {
+			fnd = c.Spndbl && !c.Chng
+		}
+	}
+	if !fnd {
+		t.Fatalf("Failed: expected the output paying the address to be spendable")
+	}
+
+	h2, err := genNd.SendTxReq(context.Background(), &wallet.TxReq{PubK: to.GetPublicKeyBytes(), Amt: 10, Fee: 50, Frm: []string{loc}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !WaitFor(func() bool { return genNd.Mnr.TxP.Ct.Load() == 1 }) {
+		t.Fatalf("Failed: spend of the output did not reach the pool")
+	}
+	if _, err := genNd.GenerateBlocks(1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := genNd.WtTxStat(h2); s.Stts != wallet.Cnfrmd {
+		t.Errorf("Failed: expected the spend of the output to be mined, got %+v", s)
+	}
+}
+
+// TestGetBalanceAddr checks that the balance of a key
+// counts payments to its address as well as ones to
+// the key itself.
+func TestGetBalanceAddr(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	genNd.Start()
+	defer genNd.Kill()
+	to, _ := id.CreateSimpleID()
+	pk := to.GetPublicKeyBytes()
+
+	if _, err := genNd.SendToAddr(context.Background(), 100, 50, id.PKAddr(id.RegtestAddrVer, pk)); err != nil {
+		t.Fatal(err)
+	}
+	if !WaitFor(func() bool { return genNd.Mnr.TxP.Ct.Load() == 1 }) {
+		t.Fatalf("Failed: payment did not reach the pool")
+	}
+	if _, err := genNd.GenerateBlocks(1, pk); err != nil {
+		t.Fatal(err)
+	}
+	sbsdy := genNd.Conf.MnrConf.InitSubsdy
+	if bal := genNd.GetBalance(hex.EncodeToString(pk)); bal != sbsdy+50+100 {
+		t.Errorf("Failed: expected a balance of %v, got %v", sbsdy+50+100, bal)
+	}
+}
@@ -0,0 +1,63 @@
+package test
+
+import (
+	"BrunoCoin/pkg/id"
+	"BrunoCoin/pkg/wallet"
+	"context"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// TestSendBatch checks that a batch payment is made
+// as one transaction with an output for every person
+// plus change, and that invalid batches and ones the
+// wallet can't pay for are refused.
+func TestSendBatch(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	genNd.Start()
+	defer genNd.Kill()
+	var pmts []*wallet.Pmt
+	for i := uint32(1); i <= 3; i++ {
+		to, _ := id.CreateSimpleID()
+		pmts = append(pmts, &wallet.Pmt{PubK: to.GetPublicKeyBytes(), Amt: 100 * i})
+	}
+
+	bad := [][]*wallet.Pmt{
+		nil,
+		{pmts[0], {PubK: pmts[1].PubK, Amt: 0}},
+		{pmts[0], {PubK: []byte("not a key"), Amt: 5}},
+		{pmts[0], pmts[1], pmts[0]},
+		{pmts[0], {Amt: 5}},
+	}
+	for i, b := range bad {
+		if _, err := genNd.SendBatch(context.Background(), b, 10); err == nil {
+			t.Errorf("Failed: invalid batch %v was accepted", i)
+		}
+	}
+	big := []*wallet.Pmt{pmts[0], {PubK: pmts[1].PubK, Amt: 1 << 31}}
+	if _, err := genNd.SendBatch(context.Background(), big, 10); !errors.Is(err, wallet.ErrInsfFnds) {
+		t.Errorf("Failed: expected a batch paying more than the balance to fail, got %v", err)
+	}
+
+	h, err := genNd.SendBatch(context.Background(), pmts, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !WaitFor(func() bool { return genNd.Mnr.TxP.Ct.Load() == 1 }) {
+		t.Fatalf("Failed: batch transaction was not accepted")
+	}
+	if _, err := genNd.GenerateBlocks(1, nil); err != nil {
+		t.Fatal(err)
+	}
+	blks := genNd.Chain.List()
+	txs := blks[len(blks)-1].Transactions
+	if len(txs) != 2 || txs[1].Hash() != h || len(txs[1].Outputs) != 4 || txs[1].Fee() != 10 {
+		t.Fatalf("Failed: expected one transaction with 3 payments and change paying a fee of 10")
+	}
+	for _, p := range pmts {
+		if bal := genNd.GetBalance(hex.EncodeToString(p.PubK)); bal != p.Amt {
+			t.Errorf("Failed: expected a payment of %v, got %v", p.Amt, bal)
+		}
+	}
+}
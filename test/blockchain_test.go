@@ -6,87 +6,54 @@ import (
 	"BrunoCoin/pkg/block/tx"
 	"BrunoCoin/pkg/blockchain"
 	"BrunoCoin/pkg/proto"
-	"BrunoCoin/pkg/utils"
+	"encoding/hex"
 	"testing"
-	"time"
 )
 
-func TestGetUTXOForAmt(t *testing.T) {
-	utils.SetDebug(true)
-
-	genNd := NewGenNd()
-	node2 := pkg.New(pkg.DefaultConfig(GetFreePort()))
-	genNd.Start()
-	node2.Start()
-
-	genNd.ConnectToPeer(node2.Addr)
-
-	time.Sleep(1 * time.Second)
-
-	genNd.SendTx(50, 100, node2.Id.GetPublicKeyBytes())
-
-	time.Sleep(time.Second * 3)
-
-	//not enough UTXO
-	utxoForTransaction, change, wasEnough := genNd.Chain.GetUTXOForAmt(1000, node2.Id.GetPublicKeyBytes())
+// MkCBBlk makes a block with only a coinbase
+// transaction, whose lock time can be changed so
+// that blocks with the same parent differ.
+func MkCBBlk(prvHsh string, lckTm uint32) *block.Block {
+	cb := proto.NewTx(0, nil, []*proto.TransactionOutput{proto.NewTxOutpt(100, "")}, lckTm)
+	return block.New(prvHsh, []*tx.Transaction{tx.Deserialize(cb)}, "")
+}
 
-	if utxoForTransaction != nil {
-		t.Fail()
-	}
-	if change != 0 {
-		t.Fail()
-	}
-	if wasEnough {
-		t.Fail()
+// TestGetUTXOForAmt checks that the chain finds
+// enough UTXO for an amount, and the change.
+func TestGetUTXOForAmt(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	node2 := pkg.New(pkg.RegtestConfig(GetFreePort()))
+	sbsdy := genNd.Conf.MnrConf.InitSubsdy
+	pk := node2.Id.GetPublicKeyBytes()
+	if _, err := genNd.GenerateBlocks(2, pk); err != nil {
+		t.Fatal(err)
 	}
 
-	utxoForTransaction, change, wasEnough := genNd.Chain.GetUTXOForAmt(10, node2.Id.GetPublicKeyBytes())
-
-	if utxoForTransaction == nil {
-		t.Fail()
-	}
-	if change != 40 {
-		t.Fail()
+	utxo, change, ok := genNd.Chain.GetUTXOForAmt(3*sbsdy, hex.EncodeToString(pk))
+	if ok || change != 0 {
+		t.Errorf("Failed: found enough UTXO for more than the balance")
 	}
-	if !wasEnough {
-		t.Fail()
+	utxo, change, ok = genNd.Chain.GetUTXOForAmt(sbsdy+1, hex.EncodeToString(pk))
+	if !ok || len(utxo) != 2 || change != sbsdy-1 {
+		t.Errorf("Failed: expected 2 UTXO and %v change, got %v and %v", sbsdy-1, len(utxo), change)
 	}
-
 }
 
+// TestAdd checks that the main chain only
+// switches to a fork once the fork is longer.
 func TestAdd(t *testing.T) {
-	utils.SetDebug(true)
-
-	genNd := NewGenNd()
-	node2 := pkg.New(pkg.DefaultConfig(GetFreePort()))
-	genNd.Start()
-	node2.Start()
-
-	genNd.ConnectToPeer(node2.Addr)
-
-	time.Sleep(1 * time.Second)
-
-	inputAmounts := 100
-	input := &proto.TransactionInput{
-		OutputIndex: 0,
-		Amount:      100,
-	}
-
-	output := &proto.TransactionOutput{
-		Amount:        100,
-		LockingScript: 0,
-	}
-	transaction := &proto.Transaction{
-		Inputs:   input,
-		Outputs:  output,
-	}
-
-	transactions := tx.Deserialize(transaction)
-	newblock := block.New(genNd.Chain.GetLastBlock().Hash(), transactions)
-	//test for invalid block
-	result := genNd.Chain.Add(newblock)
-
-	if result != block {
-		t.Fail()
+	c := blockchain.New(blockchain.DefaultConfig())
+	gen := c.GetLastBlock()
+	b1 := MkCBBlk(gen.Hash(), 1)
+	c.Add(b1)
+	f1 := MkCBBlk(gen.Hash(), 2)
+	c.Add(f1)
+	if c.Length() != 2 || c.GetLastBlock().Hash() != b1.Hash() || c.IndexOf(f1.Hash()) != 1 {
+		t.Errorf("Failed: fork of the same length replaced the main chain")
+	}
+	f2 := MkCBBlk(f1.Hash(), 2)
+	c.Add(f2)
+	if c.Length() != 3 || c.GetLastBlock().Hash() != f2.Hash() || c.List()[1].Hash() != f1.Hash() {
+		t.Errorf("Failed: longer fork did not become the main chain")
 	}
-}
\ No newline at end of file
+}
@@ -212,4 +212,31 @@ func TestBootstrapCompetingChains(t *testing.T) {
 	}
 	time.Sleep(time.Second * 5)
 	CheckChainLengths(t, nodes, []int{4, 3, 4})
-}
\ No newline at end of file
+}
+// TestBootstrapRegtest generates blocks on the genesis
+// node in regtest mode before the second node starts,
+// then bootstraps the second node.
+func TestBootstrapRegtest(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	node2 := pkg.New(pkg.RegtestConfig(GetFreePort()))
+	genNd.Start()
+	defer genNd.Kill()
+	if _, err := genNd.GenerateBlocks(4, node2.Id.GetPublicKeyBytes()); err != nil {
+		t.Fatal(err)
+	}
+	ChkMnChnLen(t, genNd, 5)
+
+	node2.Start()
+	defer node2.Kill()
+	genNd.ConnectToPeer(node2.Addr)
+	if !WaitFor(func() bool { return len(node2.PeerDb.List()) == 1 }) {
+		t.Fatal("Failed: nodes did not connect")
+	}
+	if err := node2.Bootstrap(); err != nil {
+		t.Fatal(err)
+	}
+	if !WaitFor(func() bool { return node2.Chain.Length() == 5 }) {
+		t.Errorf("Failed: node did not catch up")
+	}
+	ChkMnChnCons(t, []*pkg.Node{genNd, node2})
+}
@@ -0,0 +1,135 @@
+package test
+
+import (
+	"BrunoCoin/pkg/block/tx/txo"
+	"BrunoCoin/pkg/id"
+	"BrunoCoin/pkg/wallet"
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestCoinCtrl checks that the wallet lists its
+// outputs with whether they can be spent, pays from
+// outputs chosen by hand, and leaves frozen outputs
+// alone.
+func TestCoinCtrl(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	genNd.Start()
+	defer genNd.Kill()
+	to, _ := id.CreateSimpleID()
+	pk := to.GetPublicKeyBytes()
+	if _, err := genNd.GenerateBlocks(8, nil); err != nil {
+		t.Fatal(err)
+	}
+	gen := txo.MkTXOLoc(genNd.Chain.List()[0].Transactions[0].Hash(), 0)
+
+	cs, _ := genNd.WtCoins()
+	var mtr []string
+	var immtr string
+	for _, c := range cs {
+		switch {
+		case c.Hght == 1 && (c.Confs != 8 || !c.Spndbl):
+			t.Errorf("Failed: expected the coinbase of block 1 to be spendable with 8 confirmations, got %+v", c)
+		case c.Hght > 0 && c.Spndbl:
+			mtr = append(mtr, c.Loc())
+		case c.Immtr && !c.Spndbl:
+			immtr = c.Loc()
+		}
+	}
+	if len(cs) != 9 || len(mtr) != 4 || immtr == "" {
+		t.Fatalf("Failed: expected the genesis output, 4 mature and 4 immature coinbase outputs, got %v outputs", len(cs))
+	}
+
+	bad := [][]string{{mtr[0], immtr}, {mtr[0], mtr[0]}, {"not a locator"}}
+	for i, frm := range bad {
+		if _, err := genNd.SendTxReq(context.Background(), &wallet.TxReq{PubK: pk, Amt: 5, Fee: 2, Frm: frm}); err == nil {
+			t.Errorf("Failed: paid from invalid outputs %v", i)
+		}
+	}
+	h, err := genNd.SendTxReq(context.Background(), &wallet.TxReq{PubK: pk, Amt: 5, Fee: 2, Frm: mtr[:2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := genNd.Wallet.LmnlTxs.Get(h)
+	if len(sent.Inputs) != 2 || txo.MkTXOLoc(sent.Inputs[1].TransactionHash, sent.Inputs[1].OutputIndex) != mtr[1] || sent.Fee() != 2 {
+		t.Errorf("Failed: expected the payment to spend the 2 chosen outputs")
+	}
+
+	if err := genNd.FrzCoin("not a locator"); err == nil {
+		t.Errorf("Failed: froze an output that doesn't pay the wallet")
+	}
+	if err := genNd.FrzCoin(gen); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := genNd.SendTxCtx(context.Background(), 100, 50, pk); !errors.Is(err, wallet.ErrInsfFnds) {
+		t.Errorf("Failed: expected the frozen output not to be spent, got %v", err)
+	}
+	if _, err := genNd.SendTxReq(context.Background(), &wallet.TxReq{PubK: pk, Amt: 100, Fee: 50, Frm: []string{gen}}); err == nil {
+		t.Errorf("Failed: paid from a frozen output")
+	}
+	genNd.UnfrzCoin(gen)
+	if _, err := genNd.SendTxCtx(context.Background(), 100, 50, pk); err != nil {
+		t.Errorf("Failed: expected the unfrozen output to be spent, got %v", err)
+	}
+}
+
+// TestCnsldt checks that consolidating merges the
+// smallest outputs into one, at most MxCnsldt at a
+// time, and that the merged output is mined.
+func TestCnsldt(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	genNd.Start()
+	defer genNd.Kill()
+	if _, err := genNd.Cnsldt(context.Background(), 100, 0); err == nil {
+		t.Errorf("Failed: consolidated a single output")
+	}
+	// 8 mature coinbase outputs
+	if _, err := genNd.GenerateBlocks(12, nil); err != nil {
+		t.Fatal(err)
+	}
+	sbsdy := genNd.Chain.List()[1].Transactions[0].SumOutputs()
+
+	genNd.Conf.WtConf.MxCnsldt = 3
+	h, err := genNd.Cnsldt(context.Background(), sbsdy, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genNd.Conf.WtConf.MxCnsldt = wallet.DefaultConfig().MxCnsldt
+	h2, err := genNd.Cnsldt(context.Background(), sbsdy, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range []struct {
+		h   string
+		ins int
+	}{{h, 3}, {h2, 5}} {
+		sent := genNd.Wallet.LmnlTxs.Get(c.h)
+		if len(sent.Inputs) != c.ins || len(sent.Outputs) != 1 || !genNd.Wallet.Keys.IsChng(sent.Outputs[0].LockingScript) {
+			t.Errorf("Failed: expected consolidation %v to merge %v outputs into one change output", i, c.ins)
+		}
+		if want := uint32(genNd.Conf.WtConf.FallbkRt.Fee(sent.Sz())); sent.Fee() < want {
+			t.Errorf("Failed: expected consolidation %v to pay at least %v, got %v", i, want, sent.Fee())
+		}
+	}
+
+	if !WaitFor(func() bool { return genNd.Mnr.TxP.Ct.Load() == 2 }) {
+		t.Fatalf("Failed: consolidations did not reach the pool")
+	}
+	if _, err := genNd.GenerateBlocks(1, nil); err != nil {
+		t.Fatal(err)
+	}
+	mrgd := 0
+	cs, _ := genNd.WtCoins()
+	for _, c := range cs {
+		if c.TxHsh == h || c.TxHsh == h2 {
+			mrgd++
+			if c.Confs != 1 || !c.Spndbl {
+				t.Errorf("Failed: expected the merged output to be spendable, got %+v", c)
+			}
+		}
+	}
+	if mrgd != 2 {
+		t.Errorf("Failed: expected 2 merged outputs, got %v", mrgd)
+	}
+}
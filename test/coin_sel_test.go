@@ -0,0 +1,80 @@
+package test
+
+import (
+	"BrunoCoin/pkg/id"
+	"BrunoCoin/pkg/wallet"
+	"testing"
+)
+
+// TestSlctCoins checks that every coin selection
+// strategy pays the amount and the fee, that branch
+// and bound finds a match that needs no change, and
+// that dust change is added to the fee.
+func TestSlctCoins(t *testing.T) {
+	var us []*wallet.UTXO
+	for i, a := range []uint32{50, 30, 20, 7} {
+		us = append(us, &wallet.UTXO{TxHsh: "a", OutIdx: uint32(i), Amt: a})
+	}
+	fee := func(int, int) uint32 { return 3 }
+
+	cs, err := wallet.SlctCoins(us, 40, 1, fee, 5, wallet.LrgstFrst)
+	if err != nil || len(cs.UTXOs) != 1 || cs.UTXOs[0].Amt != 50 || cs.Fee != 3 || cs.Chng != 7 {
+		t.Errorf("Failed: largest first did not spend the largest output with change")
+	}
+	cs, err = wallet.SlctCoins(us, 24, 1, fee, 5, wallet.BnB)
+	if err != nil || len(cs.UTXOs) != 2 || cs.Chng != 0 || cs.Fee != 3 {
+		t.Errorf("Failed: branch and bound did not find the exact match 20+7")
+	}
+	cs, err = wallet.SlctCoins(us, 45, 1, fee, 5, wallet.LrgstFrst)
+	if err != nil || cs.Chng != 0 || cs.Fee != 5 {
+		t.Errorf("Failed: dust change was not added to the fee")
+	}
+	for _, s := range []wallet.Strtgy{wallet.LrgstFrst, wallet.BnB, wallet.Rnd} {
+		cs, err = wallet.SlctCoins(us, 90, 1, fee, 5, s)
+		if err != nil {
+			t.Fatalf("Failed: strategy %v could not pay 90: %v", s, err)
+		}
+		var sum uint32
+		for _, u := range cs.UTXOs {
+			sum += u.Amt
+		}
+		if sum != 90+cs.Fee+cs.Chng || cs.Fee < 3 {
+			t.Errorf("Failed: strategy %v spent %v to pay 90 with fee %v and change %v", s, sum, cs.Fee, cs.Chng)
+		}
+		if _, err := wallet.SlctCoins(us, 105, 1, fee, 5, s); err != wallet.ErrInsfFnds {
+			t.Errorf("Failed: strategy %v paid more than the wallet has", s)
+		}
+	}
+}
+
+// TestWtUTXOIdx checks that the wallet's transactions
+// pay the fee they were asked to out of the outputs in
+// its index, and that the index follows them onto the
+// chain.
+func TestWtUTXOIdx(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	genNd.Start()
+	defer genNd.Kill()
+	cs := genNd.Wallet.Coins()
+	if len(cs) != 1 || cs[0].Amt != genNd.WtBal() {
+		t.Fatalf("Failed: index did not find the genesis output")
+	}
+	to, _ := id.CreateSimpleID()
+	genNd.SendTx(100, 10, to.GetPublicKeyBytes())
+	if !WaitFor(func() bool { return genNd.Mnr.TxP.Ct.Load() == 1 }) {
+		t.Fatalf("Failed: wallet transaction was not accepted")
+	}
+	if _, err := genNd.GenerateBlocks(1, to.GetPublicKeyBytes()); err != nil {
+		t.Fatal(err)
+	}
+	blks := genNd.Chain.List()
+	txs := blks[len(blks)-1].Transactions
+	if len(txs) != 2 || txs[1].SumInputs()-txs[1].SumOutputs() != 10 {
+		t.Fatalf("Failed: mined wallet transaction did not pay the fee")
+	}
+	AsrtBal(t, genNd, cs[0].Amt-110)
+	cs2 := genNd.Wallet.Coins()
+	if len(cs2) != 1 || cs2[0].Loc() == cs[0].Loc() || cs2[0].Hght != len(blks)-1 {
+		t.Errorf("Failed: index did not replace the spent output with the change")
+	}
+}
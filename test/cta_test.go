@@ -118,9 +118,8 @@ func TestBasicSystem(t *testing.T) {
 	// trying to spend the money given to the genesis node
 	// in the genesis transaction.
 	tforinp := genNd.Chain.LastBlock.PrevNode.Block.Transactions[0]
-	unlck, _ := tforinp.Outputs[0].MkSig(genNd.Id)
 	txi := []*proto.TransactionInput{
-		proto.NewTxInpt(tforinp.Hash(), 0, unlck, tforinp.Outputs[0].Amount),
+		proto.NewTxInpt(tforinp.Hash(), 0, "", tforinp.Outputs[0].Amount),
 	}
 	amt2 := tforinp.Outputs[0].Amount - 10 - 40
 	txo := []*proto.TransactionOutput{
@@ -128,6 +127,7 @@ func TestBasicSystem(t *testing.T) {
 		proto.NewTxOutpt(amt2, fmt.Sprintf("%x", malNd.Id.GetPublicKeyBytes())),
 	}
 	txx := tx.Deserialize(proto.NewTx(0, txi, txo, 0))
+	txx.Inputs[0].UnlockingScript, _ = tforinp.Outputs[0].MkSig(genNd.Id, txx.SigHsh(0))
 
 	// Malicious node sends the invalid transaction to the
 	// network. This invalid transaction will be treated as
@@ -0,0 +1,179 @@
+package test
+
+import (
+	"BrunoCoin/pkg"
+	"BrunoCoin/pkg/fee"
+	"BrunoCoin/pkg/id"
+	"BrunoCoin/pkg/wallet"
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestSendFee checks that a sent transaction pays the
+// fee that was asked for, the fee rate that was asked
+// for, or the estimated fee rate when neither was.
+func TestSendFee(t *testing.T) {
+	to, _ := id.CreateSimpleID()
+	pk := to.GetPublicKeyBytes()
+	reqs := []struct {
+		req *wallet.TxReq
+		rt  func(n *pkg.Node) fee.Rt
+		fee uint32
+	}{
+		{&wallet.TxReq{PubK: pk, Amt: 100, Fee: 30}, nil, 30},
+		{&wallet.TxReq{PubK: pk, Amt: 100, FeeRt: 500}, func(*pkg.Node) fee.Rt { return 500 }, 0},
+		{&wallet.TxReq{PubK: pk, Amt: 100, Fee: 1, FeeRt: 500}, func(*pkg.Node) fee.Rt { return 500 }, 0},
+		{&wallet.TxReq{PubK: pk, Amt: 100}, func(n *pkg.Node) fee.Rt {
+			rt, ok := n.Wallet.EstFeeRt()
+			if !ok {
+				rt = n.Conf.WtConf.FallbkRt
+			}
+			if rt < n.Conf.MinRlyRt {
+				rt = n.Conf.MinRlyRt
+			}
+			return rt
+		}, 0},
+	}
+	for i, r := range reqs {
+		// Each send gets its own node, so that no send
+		// spends an output that an earlier one already did
+		genNd := NewRegtestGenNd()
+		genNd.Start()
+		h, err := genNd.SendTxReq(context.Background(), r.req)
+		if err != nil {
+			genNd.Kill()
+			t.Fatalf("Failed: send %v: %v", i, err)
+		}
+		sent := genNd.Wallet.LmnlTxs.Get(h)
+		want := r.fee
+		if r.rt != nil {
+			want = uint32(r.rt(genNd).Fee(sent.Sz()))
+		}
+		if sent.Fee() != want || len(sent.Outputs) != 2 {
+			t.Errorf("Failed: send %v expected a fee of %v with change, got %v", i, want, sent.Fee())
+		}
+		if !WaitFor(func() bool { return genNd.Mnr.TxP.Ct.Load() == 1 }) {
+			t.Errorf("Failed: send %v did not reach the pool", i)
+		}
+		genNd.Kill()
+	}
+}
+
+// TestSendDustChng checks that change too small to be
+// worth an output is paid as fee instead.
+func TestSendDustChng(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	to, _ := id.CreateSimpleID()
+	bal := genNd.WtBal()
+	dust := genNd.Conf.WtConf.DustLim
+	if dust < 4 {
+		t.Fatalf("Failed: expected a dust limit above the change left, got %v", dust)
+	}
+	ut, err := genNd.Wallet.MkUnsgndTx(&wallet.TxReq{PubK: to.GetPublicKeyBytes(), Amt: bal - 10 - 3, Fee: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ut.Outputs) != 1 || ut.Fee() != 13 {
+		t.Errorf("Failed: expected the change of 3 to be paid as fee, got %v outputs and a fee of %v", len(ut.Outputs), ut.Fee())
+	}
+}
+
+// TestSendEstFee checks the whole send path without
+// a fee: the transaction is made with an estimated
+// fee, relayed to another node, mined there and paid.
+func TestSendEstFee(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	node2 := pkg.New(pkg.RegtestConfig(GetFreePort()))
+	genNd.Start()
+	node2.Start()
+	defer genNd.Kill()
+	defer node2.Kill()
+	genNd.ConnectToPeer(node2.Addr)
+
+	pk, err := node2.NewRcvPK()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := genNd.SendTxCtx(context.Background(), 100, 0, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent := genNd.Wallet.LmnlTxs.Get(h); fee.FeeRt(sent) < genNd.Conf.MinRlyRt {
+		t.Errorf("Failed: estimated fee rate %v is below the minimum relay rate", fee.FeeRt(sent))
+	}
+	if !WaitFor(func() bool { return node2.Mnr.TxP.Ct.Load() == 1 }) {
+		t.Fatalf("Failed: sent transaction was not relayed")
+	}
+	if _, err := node2.GenerateBlocks(1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if bs, _ := node2.WtBals(); bs.Cnfrmd != 100 {
+		t.Errorf("Failed: expected node2 to be paid 100, got %v", bs.Cnfrmd)
+	}
+	if !WaitFor(func() bool { return genNd.Chain.Length() == 2 }) {
+		t.Errorf("Failed: genNd did not get the block paying node2")
+	}
+}
+
+// TestBumpFee checks that only transactions that
+// opted into replace-by-fee can be bumped, that a
+// bumped transaction is made again and mined in
+// place of the original, that change left too small
+// by the bump is paid as fee, and that a bump the
+// change can't pay is turned down.
+func TestBumpFee(t *testing.T) {
+	dflt := NewRegtestGenNd()
+	dflt.Start()
+	defer dflt.Kill()
+	to, _ := id.CreateSimpleID()
+	h, err := dflt.SendTxCtx(context.Background(), 100, 50, to.GetPublicKeyBytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dflt.BumpFee(context.Background(), h, 80); err == nil {
+		t.Errorf("Failed: bumped a transaction sent without opting into replace-by-fee")
+	}
+
+	c := RegtestGenConf(GetFreePort())
+	c.WtConf.RBF = true
+	genNd := pkg.New(c)
+	genNd.Start()
+	defer genNd.Kill()
+	bal := genNd.WtBal()
+
+	h, err = genNd.SendTxCtx(context.Background(), 100, 50, to.GetPublicKeyBytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !WaitFor(func() bool { return genNd.Mnr.TxP.Has(h) }) {
+		t.Fatalf("Failed: transaction did not reach the pool")
+	}
+	if _, err := genNd.BumpFee(context.Background(), h, bal-99); !errors.Is(err, wallet.ErrInsfFnds) {
+		t.Errorf("Failed: expected a fee above the change to be turned down, got %v", err)
+	}
+	if _, err := genNd.BumpFee(context.Background(), h, 50); err == nil {
+		t.Errorf("Failed: bumped to a fee that isn't higher")
+	}
+	bmpd, err := genNd.BumpFee(context.Background(), h, bal-100-3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bmpd.Outputs) != 1 || bmpd.Outputs[0].Amount != 100 || bmpd.Fee() != bal-100 {
+		t.Errorf("Failed: expected the change of 3 to be paid as fee, got %v outputs and a fee of %v", len(bmpd.Outputs), bmpd.Fee())
+	}
+	if genNd.Wallet.LmnlTxs.Get(h) != nil || genNd.Wallet.LmnlTxs.Get(bmpd.Hash()) == nil {
+		t.Errorf("Failed: expected the replacement to take the original's place")
+	}
+
+	b := bmpd.Hash()
+	if !WaitFor(func() bool { return genNd.Mnr.TxP.Has(b) && !genNd.Mnr.TxP.Has(h) }) {
+		t.Fatalf("Failed: replacement did not take the original's place in the pool")
+	}
+	if _, err := genNd.GenerateBlocks(1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := genNd.WtTxStat(b); s.Stts != wallet.Cnfrmd {
+		t.Errorf("Failed: expected the replacement to be mined, got %+v", s)
+	}
+}
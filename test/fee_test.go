@@ -0,0 +1,33 @@
+package test
+
+import (
+	"BrunoCoin/pkg/block"
+	"BrunoCoin/pkg/block/tx"
+	"BrunoCoin/pkg/fee"
+	"testing"
+)
+
+// TestEstFeeRt checks that the estimator recommends
+// the fee rate of transactions that were mined right
+// away, and not the rate of ones that never were.
+func TestEstFeeRt(t *testing.T) {
+	e := fee.New(fee.DefaultConfig())
+	if _, ok := e.EstFeeRt(1); ok {
+		t.Errorf("Failed: estimator made an estimate without any data")
+	}
+	hgh := MkPoolTx("a", 100)
+	low := MkPoolTx("b", 1)
+	e.Add(hgh, 0)
+	e.Add(low, 0)
+	e.HndlBlk(&block.Block{Transactions: []*tx.Transaction{hgh}}, 1)
+	for h := 2; h <= 5; h++ {
+		e.HndlBlk(&block.Block{}, h)
+	}
+	rt, ok := e.EstFeeRt(2)
+	if !ok {
+		t.Fatal("Failed: estimator did not make an estimate")
+	}
+	if rt != fee.FeeRt(hgh) {
+		t.Errorf("Failed: expected a fee rate of %v, got %v", fee.FeeRt(hgh), rt)
+	}
+}
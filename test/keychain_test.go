@@ -0,0 +1,108 @@
+package test
+
+import (
+	"BrunoCoin/pkg/id"
+	"BrunoCoin/pkg/wallet"
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// TestHDKey checks that keys derived from the same
+// seed along the same path are the same, and that
+// different paths give different keys.
+func TestHDKey(t *testing.T) {
+	seed := bytes.Repeat([]byte{7}, 32)
+	m1, err := id.NewMstrKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m2, _ := id.NewMstrKey(seed)
+	k1, err := m1.Drv(id.Hrdnd, 0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k2, _ := m2.Drv(id.Hrdnd, 0, 5)
+	if k1.Key.D.Cmp(k2.Key.D) != 0 || k1.Dpth != 3 || k1.Idx != 5 {
+		t.Errorf("Failed: same seed and path gave different keys")
+	}
+	k3, _ := m1.Drv(id.Hrdnd, 0, 6)
+	k4, _ := m1.Drv(0, 0, 5)
+	if k1.Key.D.Cmp(k3.Key.D) == 0 || k1.Key.D.Cmp(k4.Key.D) == 0 {
+		t.Errorf("Failed: different paths gave the same key")
+	}
+	i, err := k1.ID()
+	if err != nil || !k1.Key.PublicKey.Equal(i.GetPublicKey()) {
+		t.Errorf("Failed: id does not hold the derived key")
+	}
+	if _, err := id.NewMstrKey([]byte{1}); err == nil {
+		t.Errorf("Failed: made a master key out of a short seed")
+	}
+}
+
+// TestKeychainDscvr checks that a keychain hands out
+// fresh keys and that a keychain made from the same
+// seed finds the keys that were paid on the chain, as
+// long as they are within the gap limit.
+func TestKeychainDscvr(t *testing.T) {
+	seed := bytes.Repeat([]byte{3}, 32)
+	kc, _ := wallet.NewKeychain(seed, 5)
+	seen := make(map[string]bool)
+	var ks [][]byte
+	for i := 0; i < 10; i++ {
+		pk := kc.NewKey(wallet.Rcv).GetPublicKeyBytes()
+		if seen[string(pk)] {
+			t.Fatalf("Failed: keychain handed out the same key twice")
+		}
+		seen[string(pk)] = true
+		ks = append(ks, pk)
+	}
+	if kc.IsChng(hex.EncodeToString(ks[0])) || !kc.IsChng(hex.EncodeToString(kc.NewKey(wallet.Chng).GetPublicKeyBytes())) {
+		t.Errorf("Failed: keys were put on the wrong branch")
+	}
+
+	genNd := NewRegtestGenNd()
+	// Pays the 4th key, then the 8th, which is only
+	// within the gap limit once the 4th is found
+	for _, i := range []int{3, 7} {
+		if _, err := genNd.GenerateBlocks(1, ks[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rstrd, _ := wallet.NewKeychain(seed, 5)
+	if !rstrd.Dscvr(genNd.Chain) {
+		t.Fatalf("Failed: no paid keys were found")
+	}
+	if nxt := rstrd.NewKey(wallet.Rcv).GetPublicKeyBytes(); !bytes.Equal(nxt, ks[8]) {
+		t.Errorf("Failed: expected the key after the last paid one")
+	}
+	if rstrd.Get(hex.EncodeToString(ks[7])) == nil {
+		t.Errorf("Failed: keychain can't spend with a paid key")
+	}
+
+	far, _ := wallet.NewKeychain(seed, 2)
+	if far.Dscvr(genNd.Chain) {
+		t.Errorf("Failed: found a key past the gap limit")
+	}
+}
+
+// TestWtFreshKeys checks that the node's wallet hands
+// out fresh receive keys and counts coins paid to them.
+func TestWtFreshKeys(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	pk1, err := genNd.NewRcvPK()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk2, _ := genNd.NewRcvPK()
+	if bytes.Equal(pk1, pk2) {
+		t.Errorf("Failed: wallet handed out the same receive key twice")
+	}
+	bal := genNd.WtBal()
+	if _, err := genNd.GenerateBlocks(1, pk2); err != nil {
+		t.Fatal(err)
+	}
+	if genNd.WtBal() <= bal {
+		t.Errorf("Failed: coins paid to a fresh key were not counted")
+	}
+}
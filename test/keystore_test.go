@@ -0,0 +1,166 @@
+package test
+
+import (
+	"BrunoCoin/pkg"
+	"BrunoCoin/pkg/blockchain"
+	"BrunoCoin/pkg/id"
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestKeystore checks that a keystore only gives up
+// its private key with the right passphrase, and that
+// changing the passphrase keeps the key.
+func TestKeystore(t *testing.T) {
+	f := t.TempDir() + "/key.json"
+	i, _ := id.CreateSimpleID()
+	if _, err := id.NewKeystore(f, "pass", i, id.LightKDF); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := id.NewKeystore(f, "pass", i, id.LightKDF); err == nil {
+		t.Errorf("Failed: overwrote an existing keystore")
+	}
+	d, _ := ioutil.ReadFile(f)
+	if strings.Contains(string(d), hex.EncodeToString(i.PrivateKeyBytes)) {
+		t.Errorf("Failed: private key was written in the clear")
+	}
+
+	ks, err := id.OpenKeystore(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ks.IsLckd() || ks.GetPrivateKey() != nil {
+		t.Errorf("Failed: opened keystore is not locked")
+	}
+	if !bytes.Equal(ks.GetPublicKeyBytes(), i.PublicKeyBytes) {
+		t.Errorf("Failed: locked keystore has the wrong public key")
+	}
+	if err := ks.Unlock("wrong"); err != id.ErrWrngPass {
+		t.Errorf("Failed: expected a wrong passphrase error, got %v", err)
+	}
+	if err := ks.Unlock("pass"); err != nil || !i.PrivateKey.Equal(ks.GetPrivateKey()) {
+		t.Errorf("Failed: unlocked keystore has the wrong private key: %v", err)
+	}
+	ks.Lock()
+	if !ks.IsLckd() {
+		t.Errorf("Failed: keystore did not lock")
+	}
+
+	if err := ks.ChngPass("wrong", "new"); err != id.ErrWrngPass {
+		t.Errorf("Failed: changed the passphrase without the old one")
+	}
+	if err := ks.ChngPass("pass", "new"); err != nil {
+		t.Fatal(err)
+	}
+	ks2, _ := id.OpenKeystore(f)
+	if ks2.Unlock("pass") == nil || ks2.Unlock("new") != nil || !i.PrivateKey.Equal(ks2.GetPrivateKey()) {
+		t.Errorf("Failed: keystore was not encrypted under the new passphrase")
+	}
+}
+
+// TestKeystoreSeed checks that a seed stored in a
+// keystore is encrypted, only read back while the
+// keystore is unlocked, and kept when the passphrase
+// changes.
+func TestKeystoreSeed(t *testing.T) {
+	f := t.TempDir() + "/key.json"
+	i, _ := id.CreateSimpleID()
+	ks, err := id.NewKeystore(f, "pass", i, id.LightKDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seed, _, err := ks.Seed(); seed != nil || err != nil {
+		t.Errorf("Failed: new keystore has a seed")
+	}
+	seed := bytes.Repeat([]byte{0x5a}, 64)
+	if err := ks.SetSeed(seed, "some words"); err != nil {
+		t.Fatal(err)
+	}
+	d, _ := ioutil.ReadFile(f)
+	if strings.Contains(string(d), hex.EncodeToString(seed)) || strings.Contains(string(d), "some words") {
+		t.Errorf("Failed: seed was written in the clear")
+	}
+
+	ks2, _ := id.OpenKeystore(f)
+	if _, _, err := ks2.Seed(); err != id.ErrLckd {
+		t.Errorf("Failed: expected a locked error reading the seed, got %v", err)
+	}
+	if err := ks2.SetSeed(seed, ""); err != id.ErrLckd {
+		t.Errorf("Failed: expected a locked error storing a seed, got %v", err)
+	}
+	if err := ks2.ChngPass("pass", "new"); err != nil {
+		t.Fatal(err)
+	}
+	ks3, _ := id.OpenKeystore(f)
+	if err := ks3.Unlock("new"); err != nil {
+		t.Fatal(err)
+	}
+	if got, mnem, err := ks3.Seed(); err != nil || !bytes.Equal(got, seed) || mnem != "some words" {
+		t.Errorf("Failed: seed was not kept under the new passphrase: %x %q %v", got, mnem, err)
+	}
+}
+
+// TestKeystoreNode checks that a node keeps its key
+// in its keystore file across restarts.
+func TestKeystoreNode(t *testing.T) {
+	f := t.TempDir() + "/key.json"
+	c := pkg.NoMnrConfig(GetFreePort())
+	c.IdConf = &id.Config{KsFile: f, KsPass: "pass", LightKDF: true}
+	n1 := pkg.New(c)
+	n2 := pkg.New(c)
+	if !bytes.Equal(n1.Id.GetPublicKeyBytes(), n2.Id.GetPublicKeyBytes()) || n2.Id.GetPrivateKey() == nil {
+		t.Errorf("Failed: node did not load its key from the keystore")
+	}
+}
+
+// TestKeystoreWt checks that a node whose key is in a
+// keystore keeps its wallet's keys across restarts,
+// including ones restored from another mnemonic.
+func TestKeystoreWt(t *testing.T) {
+	f := t.TempDir() + "/key.json"
+	c := pkg.NoMnrConfig(GetFreePort())
+	c.IdConf = &id.Config{KsFile: f, KsPass: "pass", LightKDF: true}
+	n1 := pkg.New(c)
+	mnem, _ := n1.BckpWt()
+	pk1, _ := n1.NewRcvPK()
+
+	n2 := pkg.New(c)
+	if b, _ := n2.BckpWt(); b != mnem {
+		t.Errorf("Failed: restarted node has a new mnemonic")
+	}
+	if pk2, _ := n2.NewRcvPK(); !bytes.Equal(pk1, pk2) {
+		t.Errorf("Failed: restarted node derived different keys")
+	}
+
+	other, _ := id.NewMnem(128)
+	if err := n2.RstrWt(other, ""); err != nil {
+		t.Fatal(err)
+	}
+	n3 := pkg.New(c)
+	if b, _ := n3.BckpWt(); b != other {
+		t.Errorf("Failed: restored mnemonic was not kept across restarts")
+	}
+	if pk3, _ := n3.NewRcvPK(); bytes.Equal(pk1, pk3) {
+		t.Errorf("Failed: restarted node kept the keys of the old mnemonic")
+	}
+}
+
+// TestLoadInSmplID checks that a simple id loaded from
+// hex strings holds the right keys, and that
+// mismatched keys are rejected.
+func TestLoadInSmplID(t *testing.T) {
+	i, err := id.LoadInSmplID(blockchain.GENPK, blockchain.GENPVK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(i.PrivateKeyBytes) != blockchain.GENPVK {
+		t.Errorf("Failed: private key bytes are not the private key")
+	}
+	other, _ := id.CreateSimpleID()
+	if _, err := id.LoadInSmplID(hex.EncodeToString(other.PublicKeyBytes), blockchain.GENPVK); err == nil {
+		t.Errorf("Failed: loaded mismatched keys")
+	}
+}
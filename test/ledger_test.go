@@ -0,0 +1,102 @@
+package test
+
+import (
+	"BrunoCoin/pkg"
+	"BrunoCoin/pkg/id"
+	"BrunoCoin/pkg/wallet"
+	"context"
+	"testing"
+)
+
+// TestWtHist checks that the wallet's history records
+// coins it received and a payment it made, first as
+// pending and then on the chain, and that its balances
+// move between unconfirmed, locked, immature and
+// confirmed as blocks are mined.
+func TestWtHist(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	genNd.Start()
+	defer genNd.Kill()
+	gen := genNd.WtBal()
+	pk, _ := genNd.NewRcvPK()
+	if _, err := genNd.GenerateBlocks(1, pk); err != nil {
+		t.Fatal(err)
+	}
+	sbsdy := genNd.Chain.List()[1].Transactions[0].SumOutputs()
+	hist, err := genNd.WtHist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hist) != 2 || hist[1].Dir != wallet.In || !hist[1].Cb || hist[1].Amt != sbsdy || hist[1].Confs != 1 {
+		t.Fatalf("Failed: history did not record the genesis and coinbase outputs")
+	}
+	if bs, _ := genNd.WtBals(); *bs != (wallet.Bals{Cnfrmd: gen, Immtr: sbsdy}) {
+		t.Errorf("Failed: expected the coinbase output to be immature, got %+v", *bs)
+	}
+
+	to, _ := id.CreateSimpleID()
+	genNd.SendTx(100, 10, to.GetPublicKeyBytes())
+	if !WaitFor(func() bool { return genNd.Mnr.TxP.Ct.Load() == 1 }) {
+		t.Fatalf("Failed: wallet transaction was not accepted")
+	}
+	hist, _ = genNd.WtHist()
+	e := hist[len(hist)-1]
+	if len(hist) != 3 || e.Dir != wallet.Out || e.Amt != 100 || e.Fee != 10 || e.Hght != -1 || e.Confs != 0 {
+		t.Fatalf("Failed: history did not record the pending payment")
+	}
+	if bs, _ := genNd.WtBals(); *bs != (wallet.Bals{Uncnfrmd: gen - 110, Immtr: sbsdy, Lckd: gen}) {
+		t.Errorf("Failed: expected the spent output to be locked, got %+v", *bs)
+	}
+
+	if _, err := genNd.GenerateBlocks(4, to.GetPublicKeyBytes()); err != nil {
+		t.Fatal(err)
+	}
+	pndng := e.TxHsh
+	hist, _ = genNd.WtHist()
+	e = hist[len(hist)-1]
+	if len(hist) != 3 || e.TxHsh != pndng || e.Hght != 2 || e.Confs != 4 {
+		t.Errorf("Failed: history did not move the payment onto the chain")
+	}
+	if bs, _ := genNd.WtBals(); *bs != (wallet.Bals{Cnfrmd: gen - 110 + sbsdy}) {
+		t.Errorf("Failed: expected every output to be confirmed, got %+v", *bs)
+	}
+}
+
+// TestWtHistChnd checks that a pending payment
+// spending the change of another pending payment is
+// recorded as going out, not coming in.
+func TestWtHistChnd(t *testing.T) {
+	c := RegtestGenConf(GetFreePort())
+	c.WtConf.SpndUncnfrmd = true
+	genNd := pkg.New(c)
+	genNd.Start()
+	defer genNd.Kill()
+	to, _ := id.CreateSimpleID()
+
+	h1, err := genNd.SendTxReq(context.Background(), &wallet.TxReq{PubK: to.GetPublicKeyBytes(), Amt: 100, Fee: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, err := genNd.SendTxReq(context.Background(), &wallet.TxReq{PubK: to.GetPublicKeyBytes(), Amt: 50, Fee: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i := genNd.Wallet.LmnlTxs.Get(h2).Inputs[0]; i.TransactionHash != h1 {
+		t.Fatalf("Failed: expected the second payment to spend the change of the first")
+	}
+	hist, _ := genNd.WtHist()
+	amts := map[string]uint32{h1: 100, h2: 50}
+	for _, e := range hist {
+		a, ok := amts[e.TxHsh]
+		if !ok {
+			continue
+		}
+		delete(amts, e.TxHsh)
+		if e.Dir != wallet.Out || e.Amt != a || e.Fee != 10 || e.Hght != -1 {
+			t.Errorf("Failed: expected pending payment %v to go out, got %+v", a, *e)
+		}
+	}
+	if len(amts) != 0 {
+		t.Errorf("Failed: history is missing %v pending payments", len(amts))
+	}
+}
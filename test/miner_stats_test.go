@@ -0,0 +1,42 @@
+package test
+
+import (
+	"BrunoCoin/pkg/block"
+	"context"
+	"testing"
+	"time"
+)
+
+// TestMnrStatus checks that the miner counts the
+// hashes it attempts and the blocks it finds, and
+// that found blocks not on the main chain are
+// counted as stale.
+func TestMnrStatus(t *testing.T) {
+	n := NewGenNd()
+	n.Start()
+	defer n.Kill()
+
+	gen := n.Chain.GetLastBlock()
+	b := block.New(gen.Hash(), gen.Transactions, n.Mnr.DifTrg())
+	if !n.Mnr.CalcNonce(context.Background(), b) {
+		t.Fatal("Failed: miner did not find a nonce")
+	}
+	s := n.MnrStatus()
+	if s.Hshs == 0 || s.HshRt <= 0 || s.Hshs != uint64(b.Hdr.Nonce)+1 {
+		t.Errorf("Failed: expected %v hashes at a positive rate, got %v at %v", b.Hdr.Nonce+1, s.Hshs, s.HshRt)
+	}
+
+	// Index 0 of the main chain is the genesis block,
+	// so only b is stale, and nothing is known about
+	// a block at an index the chain hasn't reached
+	n.Mnr.Stats.AddFnd(b.Hash(), 0, time.Second)
+	n.Mnr.Stats.AddFnd(gen.Hash(), 0, 2*time.Second)
+	n.Mnr.Stats.AddFnd("a", 5, 3*time.Second)
+	s = n.MnrStatus()
+	if s.BlksFnd != 3 || s.BlksStl != 1 {
+		t.Errorf("Failed: expected 3 found blocks and 1 stale, got %v and %v", s.BlksFnd, s.BlksStl)
+	}
+	if s.AvgSlvTm != 2*time.Second {
+		t.Errorf("Failed: expected average solve time of 2s, got %v", s.AvgSlvTm)
+	}
+}
@@ -2,92 +2,230 @@ package test
 
 import (
 	"BrunoCoin/pkg"
+	"BrunoCoin/pkg/block"
 	"BrunoCoin/pkg/block/tx"
-	"BrunoCoin/pkg/blockchain"
 	"BrunoCoin/pkg/miner"
-	"BrunoCoin/pkg/proto"
 	"BrunoCoin/pkg/utils"
+	"encoding/hex"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 )
 
+// TestGenCBTx checks that coinbase transactions pay
+// the minting reward (halving over time) plus fees,
+// even for empty blocks, and that coinbase
+// transactions of different blocks differ.
 func TestGenCBTx(t *testing.T) {
-	utils.SetDebug(true)
-
-	genNd := NewGenNd()
-	node2 := pkg.New(pkg.DefaultConfig(GetFreePort()))
-	genNd.Conf.MnrConf.InitPOWD = utils.CalcPOWD(1)
-	genNd.Start()
-	genNd.StartMiner()
-
-	genNd.Mnr.GenCBTx()
-}
-
-func TestHndlBlk(t *testing.T) {
-	utils.SetDebug(true)
-
 	genNd := NewGenNd()
-	node2 := pkg.New(pkg.DefaultConfig(GetFreePort()))
-	genNd.Conf.MnrConf.InitPOWD = utils.CalcPOWD(1)
-	genNd.Start()
-	genNd.StartMiner()
-
-	genNd.Mnr.HndlBlk()
+	m := genNd.Mnr
+	sbsdy := m.Conf.InitSubsdy
+
+	cb := m.GenCBTx(nil)
+	if cb == nil || !cb.IsCoinbase() || cb.SumOutputs() != sbsdy {
+		t.Fatalf("Failed: expected an empty block's coinbase to pay %v, got %v", sbsdy, cb)
+	}
+	if cb.Outputs[0].LockingScript != hex.EncodeToString(genNd.Id.GetPublicKeyBytes()) {
+		t.Errorf("Failed: coinbase did not pay the miner")
+	}
+	cb2 := m.GenCBTx([]*tx.Transaction{MkPoolTx("a", 10), MkPoolTx("b", 5)})
+	if cb2.SumOutputs() != sbsdy+15 {
+		t.Errorf("Failed: expected coinbase to pay %v, got %v", sbsdy+15, cb2.SumOutputs())
+	}
+	if m.GenCBTx([]*tx.Transaction{nil}) != nil {
+		t.Errorf("Failed: made a coinbase for a nil transaction")
+	}
+
+	m.SetChnLen(m.Conf.SubsdyHlvRt)
+	cb3 := m.GenCBTx(nil)
+	if cb3.SumOutputs() != sbsdy/2 {
+		t.Errorf("Failed: expected halved reward %v, got %v", sbsdy/2, cb3.SumOutputs())
+	}
+	if cb3.Hash() == cb.Hash() {
+		t.Errorf("Failed: coinbase transactions of different blocks have the same hash")
+	}
 }
 
+// TestHndlTx checks that the miner adds transactions
+// to its pool, and turns away ones the pool won't
+// take.
 func TestHndlTx(t *testing.T) {
-	utils.SetDebug(true)
-
-	genNd := NewGenNd()
-	node2 := pkg.New(pkg.DefaultConfig(GetFreePort()))
-	genNd.Conf.MnrConf.InitPOWD = utils.CalcPOWD(1)
-	genNd.Start()
-	genNd.StartMiner()
-
+	m := miner.New(miner.DefaultConfig(-1), nil)
+	a := MkPoolTx("a", 10)
+	if !m.HndlTx(a) || !m.TxP.Has(a.Hash()) {
+		t.Fatalf("Failed: transaction was not added to the pool")
+	}
+	if m.HndlTx(a) || m.TxP.Length() != 1 {
+		t.Errorf("Failed: transaction was added to the pool twice")
+	}
 }
 
-func TestCalcPri(t *testing.T) {
-	utils.SetDebug(true)
+// TestHndlChkBlk checks that the miner removes the
+// transactions of a block from its pool, and that
+// HndlBlk also moves it onto the block.
+func TestHndlChkBlk(t *testing.T) {
+	m := miner.New(miner.DefaultConfig(-1), nil)
+	a, b := MkPoolTx("a", 10), MkPoolTx("b", 10)
+	m.HndlTx(a)
+	m.HndlTx(b)
+	blk := block.New(m.PrvHsh, []*tx.Transaction{a}, "")
+	m.HndlChkBlk(blk)
+	if m.TxP.Has(a.Hash()) || !m.TxP.Has(b.Hash()) {
+		t.Errorf("Failed: expected only the mined transaction to leave the pool")
+	}
+	ln := m.ChnLen.Load()
+	m.HndlBlk(blk)
+	if m.PrvHsh != blk.Hash() || m.ChnLen.Load() != ln+1 {
+		t.Errorf("Failed: miner did not move onto the block")
+	}
+}
 
-	genNd := NewGenNd()
-	node2 := pkg.New(pkg.DefaultConfig(GetFreePort()))
-	genNd.Conf.MnrConf.InitPOWD = utils.CalcPOWD(1)
+// TestGenerateBlocks checks that a regtest node mines
+// blocks on demand, including empty ones and ones
+// with the transactions from its pool, and that
+// the blocks reach its peers.
+func TestGenerateBlocks(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	node2 := pkg.New(pkg.RegtestConfig(GetFreePort()))
 	genNd.Start()
-	genNd.StartMiner()
-
-	genNd
-
+	node2.Start()
+	defer genNd.Kill()
+	defer node2.Kill()
+	genNd.ConnectToPeer(node2.Addr)
+	if !WaitFor(func() bool { return len(genNd.PeerDb.List()) == 1 }) {
+		t.Fatal("Failed: nodes did not connect")
+	}
+
+	sbsdy := genNd.Conf.MnrConf.InitSubsdy
+	pk := node2.Id.GetPublicKeyBytes()
+	hshs, err := genNd.GenerateBlocks(3, pk)
+	if err != nil || len(hshs) != 3 {
+		t.Fatalf("Failed: expected 3 blocks, got %v (%v)", len(hshs), err)
+	}
+	ChkMnChnLen(t, genNd, 4)
+	if genNd.Chain.GetLastBlock().Hash() != hshs[2] {
+		t.Errorf("Failed: last generated block is not the end of the main chain")
+	}
+	if !WaitFor(func() bool { return node2.Chain.Length() == 4 }) {
+		t.Errorf("Failed: peer did not get the generated blocks")
+	}
+	if bal := genNd.GetBalance(hex.EncodeToString(pk)); bal != 3*sbsdy {
+		t.Errorf("Failed: expected payee to have %v, had %v", 3*sbsdy, bal)
+	}
+
+	vld := MkGenTx(genNd, 10)
+	if !genNd.ChkTx(vld) || !genNd.Mnr.TxP.Add(vld) {
+		t.Fatal("Failed: valid transaction was not added to the pool")
+	}
+	if _, err := genNd.GenerateBlocks(1, nil); err != nil {
+		t.Fatal(err)
+	}
+	b := genNd.Chain.GetLastBlock()
+	if len(b.Transactions) != 2 || b.Transactions[1].Hash() != vld.Hash() {
+		t.Errorf("Failed: generated block did not have the pool's transaction")
+	}
+	if b.Transactions[0].SumOutputs() != sbsdy+10 {
+		t.Errorf("Failed: coinbase did not collect the fee")
+	}
+	if genNd.Mnr.TxP.Length() != 0 {
+		t.Errorf("Failed: mined transaction stayed in the pool")
+	}
+	if s := genNd.MnrStatus(); s.BlksFnd != 4 || s.BlksStl != 0 {
+		t.Errorf("Failed: expected 4 found blocks and none stale, got %v and %v", s.BlksFnd, s.BlksStl)
+	}
 }
 
-func TestAdd(t *testing.T) {
-	utils.SetDebug(true)
+// TestGenerateBlocksNoMnr checks that a node without
+// a miner can't generate blocks.
+func TestGenerateBlocksNoMnr(t *testing.T) {
+	n := pkg.New(pkg.NoMnrConfig(GetFreePort()))
+	if _, err := n.GenerateBlocks(1, nil); err == nil {
+		t.Errorf("Failed: node without a miner generated blocks")
+	}
+}
 
-	genNd := NewGenNd()
-	node2 := pkg.New(pkg.DefaultConfig(GetFreePort()))
-	genNd.Conf.MnrConf.InitPOWD = utils.CalcPOWD(1)
+// TestMnEmpty checks that a miner configured to mine
+// empty blocks grows the chain without any
+// transactions.
+func TestMnEmpty(t *testing.T) {
+	c := RegtestGenConf(GetFreePort())
+	c.MnrConf.MnEmpty = true
+	genNd := pkg.New(c)
 	genNd.Start()
 	genNd.StartMiner()
-
-	genNd.Mnr.TxP.Add()
+	if !WaitFor(func() bool { return genNd.Chain.Length() >= 3 }) {
+		t.Errorf("Failed: miner did not mine empty blocks")
+	}
+	genNd.Mnr.Pause()
+	b := genNd.Chain.GetLastBlock()
+	if len(b.Transactions) != 1 || !b.Transactions[0].IsCoinbase() {
+		t.Errorf("Failed: expected a block with only a coinbase transaction")
+	}
 }
 
-func TestChkTxs(t *testing.T) {
-	utils.SetDebug(true)
-
-	genNd := NewGenNd()
-	node2 := pkg.New(pkg.DefaultConfig(GetFreePort()))
-	genNd.Conf.MnrConf.InitPOWD = utils.CalcPOWD(1)
+// TestTmpltRfsh checks that the miner rebuilds its
+// block template on a timer, even without pool
+// updates.
+func TestTmpltRfsh(t *testing.T) {
+	c := GenConf(GetFreePort())
+	c.MnrConf.MnEmpty = true
+	c.MnrConf.NncLim = 1
+	c.MnrConf.InitPOWD = utils.CalcPOWD(28)
+	c.MnrConf.TmpltRfsh = 20 * time.Millisecond
+	genNd := pkg.New(c)
 	genNd.Start()
 	genNd.StartMiner()
+	defer genNd.Mnr.Pause()
+	if !WaitFor(func() bool { return genNd.Mnr.Stats.Tmplts.Load() >= 3 }) {
+		t.Errorf("Failed: expected the template to be refreshed, built %v", genNd.Mnr.Stats.Tmplts.Load())
+	}
 }
 
-func TestHndlChkBlk(t *testing.T) {
-	utils.SetDebug(true)
-
-	genNd := NewGenNd()
-	node2 := pkg.New(pkg.DefaultConfig(GetFreePort()))
-	genNd.Conf.MnrConf.InitPOWD = utils.CalcPOWD(1)
+// TestMnrLifecycle checks the miner's state machine
+// and that killing it while transactions and blocks
+// are still being handled neither panics nor leaves
+// it mining.
+func TestMnrLifecycle(t *testing.T) {
+	c := RegtestGenConf(GetFreePort())
+	c.MnrConf.MnEmpty = true
+	genNd := pkg.New(c)
 	genNd.Start()
-	genNd.StartMiner()
-}
\ No newline at end of file
+	m := genNd.Mnr
+	if m.State() != miner.Idle || m.Pause() || m.Resume() {
+		t.Errorf("Failed: idle miner was paused or resumed")
+	}
+	if !m.StartMiner() || m.StartMiner() || m.State() != miner.Running {
+		t.Fatalf("Failed: expected the miner to start once, state is %v", m.State())
+	}
+	if !m.Pause() || m.Pause() || m.State() != miner.Paused {
+		t.Errorf("Failed: expected the miner to pause once, state is %v", m.State())
+	}
+	if !m.Resume() || m.Resume() || m.State() != miner.Running {
+		t.Errorf("Failed: expected the miner to resume once, state is %v", m.State())
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m.HndlTx(MkPoolTx(fmt.Sprint(i), 10))
+			m.HndlChkBlk(genNd.Chain.GetLastBlock())
+		}(i)
+	}
+	genNd.Kill()
+	wg.Wait()
+	if m.State() != miner.Stopped || m.StartMiner() || m.Resume() {
+		t.Errorf("Failed: killed miner was restarted, state is %v", m.State())
+	}
+	// Let the node finish handling the last block mined
+	time.Sleep(50 * time.Millisecond)
+	ln := genNd.Chain.Length()
+	m.HndlTx(MkPoolTx("a", 10))
+	time.Sleep(50 * time.Millisecond)
+	if m.Mining.Load() || genNd.Chain.Length() != ln {
+		t.Errorf("Failed: killed miner kept mining")
+	}
+	m.Kill()
+}
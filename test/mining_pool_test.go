@@ -0,0 +1,36 @@
+package test
+
+import (
+	"BrunoCoin/pkg/block/tx"
+	"BrunoCoin/pkg/id"
+	"BrunoCoin/pkg/miner"
+	"BrunoCoin/pkg/proto"
+	"testing"
+)
+
+// TestNewMiningPoolCPFP checks that a child paying a
+// high fee pulls its low fee parent into the block
+// ahead of an unrelated transaction paying a medium
+// fee, and that the parent comes before the child.
+func TestNewMiningPoolCPFP(t *testing.T) {
+	c := miner.DefaultConfig(-1)
+	prnt := MkPoolTx("a", 1)
+	c.BlkSz = 100 + 2*prnt.Sz()
+	i, _ := id.CreateSimpleID()
+	m := miner.New(c, i)
+
+	txi := []*proto.TransactionInput{proto.NewTxInpt(prnt.Hash(), 0, "", 999)}
+	txo := []*proto.TransactionOutput{proto.NewTxOutpt(899, "")}
+	chld := tx.Deserialize(proto.NewTx(0, txi, txo, 0))
+	mdm := MkPoolTx("b", 30)
+	for _, t2 := range []*tx.Transaction{prnt, chld, mdm} {
+		if !m.TxP.Add(t2) {
+			t.Fatalf("Failed: pool did not accept %v", t2.NameTag())
+		}
+	}
+
+	mp := m.NewMiningPool()
+	if len(mp) != 2 || mp[0].Hash() != prnt.Hash() || mp[1].Hash() != chld.Hash() {
+		t.Errorf("Failed: expected the parent followed by the child, got %v transactions", len(mp))
+	}
+}
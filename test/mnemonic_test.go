@@ -0,0 +1,126 @@
+package test
+
+import (
+	"BrunoCoin/pkg/id"
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// TestMnemBIP39 checks mnemonics and seeds against
+// the BIP39 test vectors, which use the passphrase
+// "TREZOR".
+func TestMnemBIP39(t *testing.T) {
+	vs := []struct {
+		ent, mnem, seed string
+	}{
+		{"00000000000000000000000000000000",
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"},
+		{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			"legal winner thank year wave sausage worth useful legal winner thank yellow",
+			"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607"},
+		{"80808080808080808080808080808080",
+			"letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+			"d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8"},
+		{"ffffffffffffffffffffffffffffffff",
+			"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+			"ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069"},
+	}
+	for _, v := range vs {
+		ent, _ := hex.DecodeString(v.ent)
+		if mnem, err := id.EntToMnem(ent); err != nil || mnem != v.mnem {
+			t.Errorf("Failed: expected %x to make %q, got %q %v", ent, v.mnem, mnem, err)
+		}
+		if got, err := id.MnemToEnt(v.mnem); err != nil || !bytes.Equal(got, ent) {
+			t.Errorf("Failed: expected %q to turn back into %x, got %x %v", v.mnem, ent, got, err)
+		}
+		if seed, err := id.MnemToSeed(v.mnem, "TREZOR"); err != nil || hex.EncodeToString(seed) != v.seed {
+			t.Errorf("Failed: expected the seed of %q to be %v, got %x %v", v.mnem, v.seed, seed, err)
+		}
+	}
+}
+
+// TestMnem checks that mnemonics turn back into
+// their entropy, and that mistyped or swapped words
+// are caught.
+func TestMnem(t *testing.T) {
+	ent := bytes.Repeat([]byte{0x5a}, 16)
+	mnem, err := id.EntToMnem(ent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrds := strings.Fields(mnem)
+	if len(wrds) != 12 {
+		t.Fatalf("Failed: expected 12 words, got %v", len(wrds))
+	}
+	if got, err := id.MnemToEnt("  " + strings.ToUpper(mnem) + "\n"); err != nil || !bytes.Equal(got, ent) {
+		t.Errorf("Failed: mnemonic did not turn back into its entropy: %v", err)
+	}
+	wrds[0], wrds[11] = wrds[11], wrds[0]
+	if _, err := id.MnemToEnt(strings.Join(wrds, " ")); err != id.ErrMnemChk {
+		t.Errorf("Failed: expected a checksum error for swapped words, got %v", err)
+	}
+	wrds[0] = "zzzz"
+	if _, err := id.MnemToEnt(strings.Join(wrds, " ")); err == nil {
+		t.Errorf("Failed: accepted an invalid word")
+	}
+	if _, err := id.MnemToEnt(strings.Join(wrds[:11], " ")); err == nil {
+		t.Errorf("Failed: accepted the wrong number of words")
+	}
+
+	long, err := id.NewMnem(256)
+	if err != nil || len(strings.Fields(long)) != 24 {
+		t.Fatalf("Failed: expected a 24 word mnemonic: %v", err)
+	}
+	if _, err := id.NewMnem(100); err == nil {
+		t.Errorf("Failed: made a mnemonic out of an invalid number of bits")
+	}
+	s1, _ := id.MnemToSeed(long, "")
+	s2, _ := id.MnemToSeed(long, "pass")
+	if len(s1) != 64 || bytes.Equal(s1, s2) {
+		t.Errorf("Failed: passphrase did not change the seed")
+	}
+}
+
+// TestRstrWt checks that restoring a wallet from its
+// mnemonic finds the coins paid to its keys again.
+func TestRstrWt(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	var pk []byte
+	for i := 0; i < 3; i++ {
+		pk, _ = genNd.NewRcvPK()
+	}
+	mnem, err := genNd.BckpWt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := genNd.GenerateBlocks(2, pk); err != nil {
+		t.Fatal(err)
+	}
+	bal := genNd.WtBal()
+
+	other, _ := id.NewMnem(128)
+	if err := genNd.RstrWt(other, ""); err != nil {
+		t.Fatal(err)
+	}
+	if genNd.WtBal() >= bal {
+		t.Errorf("Failed: wallet restored from another mnemonic kept the coins")
+	}
+	if err := genNd.RstrWt(mnem, "pass"); err != nil || genNd.WtBal() >= bal {
+		t.Errorf("Failed: wallet restored with the wrong passphrase found the coins")
+	}
+	if err := genNd.RstrWt(mnem, ""); err != nil {
+		t.Fatal(err)
+	}
+	if genNd.WtBal() != bal {
+		t.Errorf("Failed: expected a balance of %v after restoring, got %v", bal, genNd.WtBal())
+	}
+	if b, _ := genNd.BckpWt(); b != mnem {
+		t.Errorf("Failed: backup is not the restored mnemonic")
+	}
+	if err := genNd.RstrWt("not a mnemonic", ""); err == nil {
+		t.Errorf("Failed: restored from an invalid mnemonic")
+	}
+}
@@ -0,0 +1,61 @@
+package test
+
+import (
+	"BrunoCoin/pkg/block"
+	"BrunoCoin/pkg/blockchain"
+	"BrunoCoin/pkg/miner"
+	"BrunoCoin/pkg/pow"
+	"BrunoCoin/pkg/utils"
+	"context"
+	"encoding/hex"
+	"testing"
+)
+
+// TestScrypt checks scrypt against the test vectors
+// from RFC 7914.
+func TestScrypt(t *testing.T) {
+	vs := []struct {
+		pw, salt string
+		N, r, p  int
+		k        string
+	}{
+		{"", "", 16, 1, 1, "77d6576238657b203b19ca42c18a0497f16b4844e3074ae8dfdffa3fede21442fcd0069ded0948f8326a753a0fc81f17e8d3e0fb2e0d3628cf35e20c38d18906"},
+		{"password", "NaCl", 1024, 8, 16, "fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b3731622eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640"},
+	}
+	for _, v := range vs {
+		k, err := utils.Scrypt([]byte(v.pw), []byte(v.salt), v.N, v.r, v.p, 64)
+		if err != nil || hex.EncodeToString(k) != v.k {
+			t.Errorf("Failed: scrypt(%q, %q) = %x, %v", v.pw, v.salt, k, err)
+		}
+	}
+	if _, err := utils.Scrypt(nil, nil, 15, 1, 1, 32); err == nil {
+		t.Errorf("Failed: scrypt accepted N that is not a power of two")
+	}
+}
+
+// TestMinerPOW checks that the miner finds nonces
+// that satisfy each proof of work algorithm, and
+// that the trivial algorithm accepts every block.
+func TestMinerPOW(t *testing.T) {
+	c := miner.DefaultConfig(-1)
+	c.InitPOWD = utils.CalcPOWD(0)
+	gen := blockchain.GenesisBlock(blockchain.DefaultConfig())
+	for _, nm := range []string{pow.SHA256Nm, pow.SHA256dNm, pow.ScryptNm} {
+		m := miner.New(c, nil)
+		m.POW = pow.Get(nm)
+		b := block.New(gen.Hash(), gen.Transactions, m.DifTrg())
+		if !m.CalcNonce(context.Background(), b) {
+			t.Errorf("Failed: miner did not find a nonce for %v", nm)
+			continue
+		}
+		if !b.SatisfiesPOW(pow.Get(nm), m.DifTrg()) || !pow.Lss(m.POW.Hash(b.HdrByts()), m.DifTrg()) {
+			t.Errorf("Failed: block mined with %v does not satisfy it", nm)
+		}
+	}
+	if pow.Get("a") != nil {
+		t.Errorf("Failed: got an algorithm for an unknown name")
+	}
+	if !gen.SatisfiesPOW(pow.Get(pow.TrivialNm), utils.CalcPOWD(28)) {
+		t.Errorf("Failed: trivial proof of work rejected a block")
+	}
+}
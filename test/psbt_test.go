@@ -0,0 +1,109 @@
+package test
+
+import (
+	"BrunoCoin/pkg"
+	"BrunoCoin/pkg/block/tx"
+	"BrunoCoin/pkg/block/tx/txo"
+	"BrunoCoin/pkg/id"
+	"BrunoCoin/pkg/proto"
+	"BrunoCoin/pkg/wallet"
+	"encoding/hex"
+	"testing"
+)
+
+// TestPSBT checks that a watch-only node can make a
+// PSBT spending a cold key's coins, that the PSBT can
+// be signed offline, combined, finalized and
+// broadcast, and that it is mined.
+func TestPSBT(t *testing.T) {
+	c := RegtestGenConf(GetFreePort())
+	c.WtConf.WtchOnly = true
+	wtch := pkg.New(c)
+	wtch.Start()
+	defer wtch.Kill()
+	cold, _ := id.CreateSimpleID()
+	if err := wtch.WtchPK(cold.GetPublicKeyBytes()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wtch.GenerateBlocks(8, cold.GetPublicKeyBytes()); err != nil {
+		t.Fatal(err)
+	}
+	sbsdy := wtch.Chain.List()[1].Transactions[0].SumOutputs()
+
+	to, _ := id.CreateSimpleID()
+	p, err := wtch.MkPSBT(sbsdy+1, 2, to.GetPublicKeyBytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wtch.SgnPSBT(p); err != wallet.ErrWtchOnly {
+		t.Errorf("Failed: watch-only node signed a PSBT")
+	}
+	if _, err := p.Fnlz(); err == nil {
+		t.Errorf("Failed: finalized an unsigned PSBT")
+	}
+
+	// The offline machine signs a copy of the PSBT
+	offln, err := wallet.DecdPSBT(p.Encd())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := offln.SgnWith(cold); err != nil || n != len(p.Ins) || !offln.IsCmplt() {
+		t.Fatalf("Failed: expected the cold key to sign all %v inputs", len(p.Ins))
+	}
+	sgnd, err := wallet.DecdPSBT(offln.Encd())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	othr, _ := wtch.MkPSBT(sbsdy, 2, to.GetPublicKeyBytes())
+	if err := p.Cmbn(othr); err == nil {
+		t.Errorf("Failed: combined PSBTs of different transactions")
+	}
+	if err := p.Cmbn(sgnd); err != nil || !p.IsCmplt() {
+		t.Fatalf("Failed: combining did not add the signatures: %v", err)
+	}
+	h, err := wtch.BrdcstPSBT(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hist, _ := wtch.WtHist(); hist[len(hist)-1].TxHsh != h || hist[len(hist)-1].Hght != -1 {
+		t.Errorf("Failed: watch-only wallet did not track the broadcast transaction")
+	}
+	if !WaitFor(func() bool { return wtch.Mnr.TxP.Ct.Load() == 1 }) {
+		t.Fatalf("Failed: broadcast transaction did not reach the pool")
+	}
+	if _, err := wtch.GenerateBlocks(1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if bal := wtch.GetBalance(hex.EncodeToString(to.GetPublicKeyBytes())); bal != sbsdy+1 {
+		t.Errorf("Failed: expected the recipient to be paid %v, got %v", sbsdy+1, bal)
+	}
+}
+
+// TestPSBTP2PKH checks that a signer signs inputs
+// spending outputs paid to the hash of its key, as
+// well as ones paid to the key itself.
+func TestPSBTP2PKH(t *testing.T) {
+	k, _ := id.CreateSimpleID()
+	pk := k.GetPublicKeyBytes()
+	spnt := []*txo.TransactionOutput{
+		{Amount: 100, LockingScript: txo.MkP2PKH(id.PKHsh(pk))},
+		{Amount: 50, LockingScript: hex.EncodeToString(pk)},
+	}
+	ins := []*proto.TransactionInput{proto.NewTxInpt("a", 0, "", 100), proto.NewTxInpt("b", 0, "", 50)}
+	outs := []*proto.TransactionOutput{proto.NewTxOutpt(140, hex.EncodeToString(pk))}
+	p, err := wallet.NewPSBT(tx.Deserialize(proto.NewTx(0, ins, outs, 0)), spnt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	othr, _ := id.CreateSimpleID()
+	if n, _ := p.SgnWith(othr); n != 0 {
+		t.Errorf("Failed: signed inputs with a key they don't pay")
+	}
+	if n, err := p.SgnWith(k); err != nil || n != 2 || !p.IsCmplt() {
+		t.Fatalf("Failed: expected both inputs to be signed, signed %v: %v", n, err)
+	}
+	if _, err := p.Fnlz(); err != nil {
+		t.Errorf("Failed: could not finalize the signed PSBT: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package test
+
+import (
+	"BrunoCoin/pkg"
+	"BrunoCoin/pkg/block/tx/txo"
+	"BrunoCoin/pkg/id"
+	"BrunoCoin/pkg/wallet"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// TestUTXORsrv checks that outputs spent by the
+// wallet's pending transactions aren't spent again,
+// even by sends made at the same time.
+func TestUTXORsrv(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	genNd.Start()
+	defer genNd.Kill()
+	to, _ := id.CreateSimpleID()
+	pk := to.GetPublicKeyBytes()
+
+	bal := genNd.WtBal()
+	h, err := genNd.SendTxCtx(context.Background(), 100, 50, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := genNd.SendTxCtx(context.Background(), 100, 50, pk); !errors.Is(err, wallet.ErrInsfFnds) {
+		t.Errorf("Failed: expected the only output to be reserved, got %v", err)
+	}
+	chng := genNd.Wallet.LmnlTxs.Get(h).Outputs[1].Amount
+	if bs, _ := genNd.WtBals(); bs.Lckd != bal || bs.Uncnfrmd != chng || bs.Cnfrmd != 0 {
+		t.Errorf("Failed: expected %v locked and %v unconfirmed, got %+v", bal, chng, bs)
+	}
+
+	if !WaitFor(func() bool { return genNd.Mnr.TxP.Ct.Load() == 1 }) {
+		t.Fatalf("Failed: first transaction did not reach the pool")
+	}
+	// 8 blocks mine the first transaction and make 4
+	// mature coinbase outputs, each enough for a send
+	if _, err := genNd.GenerateBlocks(8, nil); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	hs := make([]string, 4)
+	for i := range hs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			hs[i], err = genNd.SendTxCtx(context.Background(), 1, 2, pk)
+			if err != nil {
+				t.Errorf("Failed: concurrent send %v: %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	spnt := make(map[string]bool)
+	for i, h := range hs {
+		sent := genNd.Wallet.LmnlTxs.Get(h)
+		if sent == nil {
+			t.Fatalf("Failed: concurrent send %v is not liminal", i)
+		}
+		for _, in := range sent.Inputs {
+			loc := txo.MkTXOLoc(in.TransactionHash, in.OutputIndex)
+			if spnt[loc] {
+				t.Errorf("Failed: two concurrent sends spent %v", loc)
+			}
+			spnt[loc] = true
+		}
+	}
+	if !WaitFor(func() bool { return genNd.Mnr.TxP.Ct.Load() == 4 }) {
+		t.Errorf("Failed: expected all 4 transactions in the pool, got %v", genNd.Mnr.TxP.Ct.Load())
+	}
+}
+
+// TestSpndUncnfrmd checks that a wallet allowed to
+// spend unconfirmed change does, and that both
+// transactions are mined.
+func TestSpndUncnfrmd(t *testing.T) {
+	c := RegtestGenConf(GetFreePort())
+	c.WtConf.SpndUncnfrmd = true
+	c.WtConf.RBF = true
+	genNd := pkg.New(c)
+	genNd.Start()
+	defer genNd.Kill()
+	to, _ := id.CreateSimpleID()
+	pk := to.GetPublicKeyBytes()
+
+	h, err := genNd.SendTxCtx(context.Background(), 100, 50, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, err := genNd.SendTxCtx(context.Background(), 100, 50, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent := genNd.Wallet.LmnlTxs.Get(h2); sent.Inputs[0].TransactionHash != h {
+		t.Errorf("Failed: expected the second transaction to spend the change of the first")
+	}
+	if _, err := genNd.BumpFee(context.Background(), h, 80); err == nil {
+		t.Errorf("Failed: bumped the fee of a transaction whose change is spent")
+	}
+	if !WaitFor(func() bool { return genNd.Mnr.TxP.Ct.Load() == 2 }) {
+		t.Fatalf("Failed: expected both transactions in the pool, got %v", genNd.Mnr.TxP.Ct.Load())
+	}
+	if _, err := genNd.GenerateBlocks(1, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range []string{h, h2} {
+		if s, _ := genNd.WtTxStat(h); s.Stts != wallet.Cnfrmd {
+			t.Errorf("Failed: expected %v to be mined, got %+v", h, s)
+		}
+	}
+}
+
+// TestRsrvDrp checks that the outputs of a dropped
+// transaction are released.
+func TestRsrvDrp(t *testing.T) {
+	c := RegtestGenConf(GetFreePort())
+	c.WtConf.RBF = true
+	genNd := pkg.New(c)
+	genNd.Start()
+	defer genNd.Kill()
+	to, _ := id.CreateSimpleID()
+
+	h, err := genNd.SendTxCtx(context.Background(), 100, 50, to.GetPublicKeyBytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !WaitFor(func() bool { s, _ := genNd.WtTxStat(h); return s.Stts == wallet.InPool }) {
+		t.Fatalf("Failed: transaction was never seen in the pool")
+	}
+	// Spends the same genesis output, paying more, so it
+	// replaces the wallet's transaction
+	genNd.HndlWtTx(MkGenTx(genNd, 1000))
+	if !WaitFor(func() bool { s, _ := genNd.WtTxStat(h); return s.Stts == wallet.Drppd }) {
+		t.Fatalf("Failed: expected the transaction pushed out of the pool to be dropped")
+	}
+	if len(genNd.Wallet.LmnlTxs.List()) != 0 {
+		t.Errorf("Failed: dropped transaction is still liminal")
+	}
+	if bs, _ := genNd.WtBals(); bs.Lckd != 0 || bs.Uncnfrmd != 0 {
+		t.Errorf("Failed: expected the dropped transaction's outputs to be released, got %+v", bs)
+	}
+}
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"BrunoCoin/pkg"
+	"BrunoCoin/pkg/blockchain"
+	"BrunoCoin/pkg/id"
+	"BrunoCoin/pkg/wallet"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestSendTxCtx checks that a synchronous send
+// returns the hash of the transaction it made, or an
+// error saying why it couldn't make one.
+func TestSendTxCtx(t *testing.T) {
+	c := RegtestGenConf(GetFreePort())
+	c.MinRlyRt = 200
+	genNd := pkg.New(c)
+	genNd.Start()
+	defer genNd.Kill()
+	ctx := context.Background()
+	to, _ := id.CreateSimpleID()
+	pk := to.GetPublicKeyBytes()
+
+	errs := []struct {
+		amt uint32
+		fee uint32
+		pk  []byte
+		err error
+	}{
+		{100, 50, nil, wallet.ErrInvldRcpnt},
+		{100, 50, []byte("not a key"), wallet.ErrInvldRcpnt},
+		{0, 50, pk, wallet.ErrInvldAmt},
+		{genNd.WtBal(), 50, pk, wallet.ErrInsfFnds},
+		{100, 5, pk, wallet.ErrFeeTooLow},
+	}
+	for i, e := range errs {
+		if _, err := genNd.SendTxCtx(ctx, e.amt, e.fee, e.pk); !errors.Is(err, e.err) {
+			t.Errorf("Failed: send %v expected %q, got %v", i, e.err, err)
+		}
+	}
+
+	h, err := genNd.SendTxCtx(ctx, 100, 50, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !WaitFor(func() bool { return genNd.Mnr.TxP.Ct.Load() == 1 }) {
+		t.Fatalf("Failed: sent transaction did not reach the pool")
+	}
+	if hist, _ := genNd.WtHist(); hist[len(hist)-1].TxHsh != h {
+		t.Errorf("Failed: returned hash is not the hash of the sent transaction")
+	}
+}
+
+// TestSendTxCtxCncl checks that a synchronous send
+// gives up when its context is cancelled, and that a
+// wallet with a locked key refuses to send.
+func TestSendTxCtxCncl(t *testing.T) {
+	to, _ := id.CreateSimpleID()
+	// Never started, so nothing takes the transaction
+	genNd := NewRegtestGenNd()
+	ctx, cncl := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cncl()
+	if _, err := genNd.SendTxCtx(ctx, 100, 10, to.GetPublicKeyBytes()); err != context.DeadlineExceeded {
+		t.Errorf("Failed: expected the send to time out, got %v", err)
+	}
+	if len(genNd.Wallet.LmnlTxs.List()) != 0 {
+		t.Errorf("Failed: wallet kept a transaction that was never sent")
+	}
+
+	gen, _ := id.LoadInSmplID(blockchain.GENPK, blockchain.GENPVK)
+	ks, err := id.NewKeystore(t.TempDir()+"/key.json", "pass", gen, id.LightKDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks.Lock()
+	c := RegtestGenConf(GetFreePort())
+	c.CstmIDObj = ks
+	lckd := pkg.New(c)
+	if _, err := lckd.SendTxCtx(context.Background(), 100, 10, to.GetPublicKeyBytes()); !errors.Is(err, wallet.ErrLckd) {
+		t.Errorf("Failed: expected the locked key to refuse to sign, got %v", err)
+	}
+}
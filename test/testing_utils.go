@@ -6,13 +6,14 @@ import (
 	"BrunoCoin/pkg/block/tx"
 	"BrunoCoin/pkg/blockchain"
 	"BrunoCoin/pkg/id"
+	"BrunoCoin/pkg/proto"
 	"BrunoCoin/pkg/utils"
-	"encoding/hex"
 	"fmt"
 	"github.com/phayes/freeport"
 	"log"
 	"os"
 	"testing"
+	"time"
 )
 
 func GetFreePort() int {
@@ -30,6 +31,15 @@ func GenConf(port int) *pkg.Config {
 	return c
 }
 
+// RegtestGenConf is GenConf in regtest mode, so blocks
+// can be generated instantly with GenerateBlocks.
+func RegtestGenConf(port int) *pkg.Config {
+	c := pkg.RegtestConfig(port)
+	c.CstmID = true
+	c.CstmIDObj, _ = id.LoadInSmplID(blockchain.GENPK, blockchain.GENPVK)
+	return c
+}
+
 // First node is always the genesis node
 func NewCluster(n int) []*pkg.Node {
 	cluster := []*pkg.Node{NewGenNd()}
@@ -71,6 +81,36 @@ func NewGenNd() *pkg.Node {
 }
 
 
+func NewRegtestGenNd() *pkg.Node {
+	return pkg.New(RegtestGenConf(GetFreePort()))
+}
+
+// WaitFor polls a condition until it is true or a
+// second has passed, since blocks and transactions
+// reach other nodes asynchronously.
+func WaitFor(cond func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return cond()
+}
+
+// MkGenTx makes a valid transaction spending the
+// genesis output (the genesis node's money), paying
+// the rest back to the genesis node minus a fee.
+func MkGenTx(n *pkg.Node, fee uint32) *tx.Transaction {
+	gen := n.Chain.List()[0].Transactions[0]
+	txi := []*proto.TransactionInput{proto.NewTxInpt(gen.Hash(), 0, "", gen.Outputs[0].Amount)}
+	txo := []*proto.TransactionOutput{proto.NewTxOutpt(gen.Outputs[0].Amount-fee, gen.Outputs[0].LockingScript)}
+	t := tx.Deserialize(proto.NewTx(0, txi, txo, 0))
+	t.Inputs[0].UnlockingScript, _ = gen.Outputs[0].MkSig(n.Id, t.SigHsh(0))
+	return t
+}
+
+
 func ChkNdPrs(t *testing.T, n *pkg.Node, prs []*pkg.Node) {
 	for _, pr := range prs {
 		if !n.PeerDb.In(pr.Addr) {
@@ -81,8 +121,11 @@ func ChkNdPrs(t *testing.T, n *pkg.Node, prs []*pkg.Node) {
 
 
 func ChkTxSeenLen(t *testing.T, n *pkg.Node, ln int) {
-	if len(n.TxMap) != ln {
-		t.Errorf("Failed: Node was expected to see %v txs, but has only seen %v", ln, len(n.TxMap))
+	n.TxMapMutex.Lock()
+	seen := len(n.TxMap)
+	n.TxMapMutex.Unlock()
+	if seen != ln {
+		t.Errorf("Failed: Node was expected to see %v txs, but has only seen %v", ln, seen)
 	}
 }
 
@@ -131,7 +174,9 @@ func StrtDmyRPCSrv(n *pkg.Node) {
 }
 
 func SndDmyTx(n *pkg.Node, t *tx.Transaction) {
+	n.TxMapMutex.Lock()
 	n.TxMap[t.Hash()] = true
+	n.TxMapMutex.Unlock()
 	for _, a := range n.PeerDb.List() {
 		utils.Debug.Printf("%v {MALICIOUS} sending %v to peer %v.\n", utils.FmtAddr(n.Addr), t.NameTag(), utils.FmtAddr(a.Addr.Addr))
 		a.Addr.ForwardTransactionRPC(t.Serialize())
@@ -140,9 +185,8 @@ func SndDmyTx(n *pkg.Node, t *tx.Transaction) {
 
 
 func AsrtBal(t *testing.T, n *pkg.Node, a uint32) {
-	pk := hex.EncodeToString(n.Id.GetPublicKeyBytes())
-	if n.GetBalance(pk) != a {
-		t.Errorf("Failed: Node {%v} was expected to have a balance of %v, but had a balance of %v\n", n.Addr, a, n.GetBalance(pk))
+	if n.WtBal() != a {
+		t.Errorf("Failed: Node {%v} was expected to have a balance of %v, but had a balance of %v\n", n.Addr, a, n.WtBal())
 	}
 }
 
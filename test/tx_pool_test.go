@@ -0,0 +1,264 @@
+package test
+
+import (
+	"BrunoCoin/pkg"
+	"BrunoCoin/pkg/block"
+	"BrunoCoin/pkg/block/tx"
+	"BrunoCoin/pkg/fee"
+	"BrunoCoin/pkg/miner"
+	"BrunoCoin/pkg/proto"
+	"context"
+	"testing"
+	"time"
+)
+
+// MkPoolTx makes a transaction spending a single
+// (made up) output worth 1000 with the inputted fee.
+func MkPoolTx(prvHsh string, fee uint32) *tx.Transaction {
+	txi := []*proto.TransactionInput{proto.NewTxInpt(prvHsh, 0, "", 1000)}
+	txo := []*proto.TransactionOutput{proto.NewTxOutpt(1000-fee, "")}
+	return tx.Deserialize(proto.NewTx(0, txi, txo, 0))
+}
+
+// TestTxPoolEvictsLowestRt fills a pool of two
+// and checks that a higher fee transaction evicts
+// the lowest fee transaction (and its child), while
+// a lower fee one is turned away.
+func TestTxPoolEvictsLowestRt(t *testing.T) {
+	c := miner.DefaultConfig(-1)
+	c.TxPCap = 2
+	tp := miner.NewTxPool(c)
+
+	low := MkPoolTx("a", 10)
+	child := MkPoolTx(low.Hash(), 20)
+	if !tp.Add(low) || !tp.Add(child) {
+		t.Fatal("Failed: pool did not accept transactions while it had room")
+	}
+	if tp.Add(MkPoolTx("b", 5)) {
+		t.Errorf("Failed: pool accepted a transaction with a lower fee rate than everything in it")
+	}
+	high := MkPoolTx("c", 50)
+	if !tp.Add(high) {
+		t.Fatal("Failed: pool did not accept a higher fee rate transaction")
+	}
+	if tp.TxQ.Has(low) || tp.TxQ.Has(child) {
+		t.Errorf("Failed: lowest fee rate transaction and its child were not evicted")
+	}
+	if tp.Length() != 1 || tp.CurSz.Load() != high.Sz() {
+		t.Errorf("Failed: pool has %v transactions of size %v", tp.Length(), tp.CurSz.Load())
+	}
+	if tp.MinRt() <= fee.FeeRt(low) {
+		t.Errorf("Failed: minimum fee rate %v was not raised above the evicted fee rate", tp.MinRt())
+	}
+	if tp.Add(MkPoolTx("d", 10)) {
+		t.Errorf("Failed: pool accepted a transaction below its minimum fee rate")
+	}
+}
+
+// TestTxPoolEvictsByPkg checks that a parent is
+// scored along with the child paying for it, so a
+// cheaper transaction on its own is evicted first.
+func TestTxPoolEvictsByPkg(t *testing.T) {
+	c := miner.DefaultConfig(-1)
+	c.TxPCap = 3
+	tp := miner.NewTxPool(c)
+
+	prnt := MkPoolTx("a", 10)
+	child := MkPoolTx(prnt.Hash(), 200)
+	alone := MkPoolTx("b", 40)
+	if !tp.Add(prnt) || !tp.Add(child) || !tp.Add(alone) {
+		t.Fatal("Failed: pool did not accept transactions while it had room")
+	}
+	if !tp.Add(MkPoolTx("c", 60)) {
+		t.Fatal("Failed: pool did not accept a higher fee rate transaction")
+	}
+	if tp.TxQ.Has(alone) {
+		t.Errorf("Failed: lowest fee rate package was not evicted")
+	}
+	if !tp.TxQ.Has(prnt) || !tp.TxQ.Has(child) {
+		t.Errorf("Failed: parent paid for by its child was evicted")
+	}
+}
+
+// TestTxPoolShldMn checks that the pool is worth
+// mining once its transactions pay enough fees in
+// total, or once a transaction has waited too long.
+func TestTxPoolShldMn(t *testing.T) {
+	c := miner.DefaultConfig(-1)
+	c.FeeLim = 30
+	c.MxWt = 50 * time.Millisecond
+	tp := miner.NewTxPool(c)
+	if tp.ShldMn() {
+		t.Errorf("Failed: empty pool should not be mined")
+	}
+	tp.Add(MkPoolTx("a", 10))
+	if tp.ShldMn() {
+		t.Errorf("Failed: pool should not be mined before paying enough fees")
+	}
+	tp.Add(MkPoolTx("b", 20))
+	if !tp.ShldMn() {
+		t.Errorf("Failed: pool paying %v in fees should be mined", tp.CurFees.Load())
+	}
+
+	tp = miner.NewTxPool(c)
+	tp.Add(MkPoolTx("a", 1))
+	if tp.ShldMn() {
+		t.Errorf("Failed: pool should not be mined before waiting long enough")
+	}
+	time.Sleep(2 * c.MxWt)
+	if !tp.ShldMn() {
+		t.Errorf("Failed: pool should be mined once a transaction has waited too long")
+	}
+}
+
+// TestTxPoolExpire checks that transactions are
+// dropped from the pool once they are too old.
+func TestTxPoolExpire(t *testing.T) {
+	c := miner.DefaultConfig(-1)
+	c.TxPExp = 50 * time.Millisecond
+	tp := miner.NewTxPool(c)
+	if !tp.Add(MkPoolTx("a", 10)) {
+		t.Fatal("Failed: pool did not accept transaction")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if exp := tp.Expire(); len(exp) != 1 || tp.Length() != 0 {
+		t.Errorf("Failed: expected 1 expired transaction, got %v with %v left", len(exp), tp.Length())
+	}
+}
+
+// MkRBFPoolTx makes the same transaction as MkPoolTx,
+// except that it signals replace-by-fee.
+func MkRBFPoolTx(prvHsh string, fee uint32) *tx.Transaction {
+	p := MkPoolTx(prvHsh, fee).Serialize()
+	p.Inputs[0].SequenceNumber = proto.RBFSeq
+	return tx.Deserialize(p)
+}
+
+// TestTxPoolRBF checks that a conflicting transaction
+// replaces one in the pool only if the original signals
+// replace-by-fee and the replacement pays more.
+func TestTxPoolRBF(t *testing.T) {
+	tp := miner.NewTxPool(miner.DefaultConfig(-1))
+	orig := MkRBFPoolTx("a", 10)
+	if !tp.Add(orig) {
+		t.Fatal("Failed: pool did not accept transaction")
+	}
+	if tp.Add(MkRBFPoolTx("a", 5)) {
+		t.Errorf("Failed: pool accepted a replacement paying a lower fee")
+	}
+	rplc := MkRBFPoolTx("a", 20)
+	if !tp.Add(rplc) {
+		t.Fatal("Failed: pool did not accept a replacement paying a higher fee")
+	}
+	if tp.TxQ.Has(orig) || !tp.TxQ.Has(rplc) || tp.Length() != 1 {
+		t.Errorf("Failed: original transaction was not replaced")
+	}
+
+	// An input whose sequence number was never set
+	// doesn't opt in
+	unst := MkPoolTx("e", 10).Serialize()
+	unst.Inputs[0].SequenceNumber = 0
+	if tx.Deserialize(unst).SignalsRBF() {
+		t.Errorf("Failed: a sequence number of 0 signals replace-by-fee")
+	}
+
+	final := MkPoolTx("b", 10)
+	if !tp.Add(final) {
+		t.Fatal("Failed: pool did not accept transaction")
+	}
+	if tp.Add(MkPoolTx("b", 50)) {
+		t.Errorf("Failed: pool replaced a transaction that did not signal replace-by-fee")
+	}
+
+	// A mined transaction evicts what it conflicts with
+	tp.ChkTxs([]*tx.Transaction{MkPoolTx("a", 1)})
+	if tp.TxQ.Has(rplc) || tp.Length() != 1 {
+		t.Errorf("Failed: transaction conflicting with a mined one stayed in the pool")
+	}
+}
+
+// TestTxPoolRBFFull checks that a replacement that
+// doesn't fit in a full pool is turned away without
+// evicting the transaction it would have replaced.
+func TestTxPoolRBFFull(t *testing.T) {
+	orig := MkRBFPoolTx("a", 10)
+	othr := MkPoolTx("b", 500)
+	c := miner.DefaultConfig(-1)
+	c.TxPMxSz = orig.Sz() + othr.Sz()
+	tp := miner.NewTxPool(c)
+	if !tp.Add(orig) || !tp.Add(othr) {
+		t.Fatal("Failed: pool did not accept transactions while it had room")
+	}
+	// Pays more than the original, but is bigger and
+	// pays less than the transaction it would have to
+	// evict for room
+	p := MkRBFPoolTx("a", 30).Serialize()
+	p.Outputs[0].Amount -= 1
+	p.Outputs = append(p.Outputs, proto.NewTxOutpt(1, ""))
+	rplc := tx.Deserialize(p)
+	if tp.Add(rplc) {
+		t.Fatal("Failed: pool accepted a replacement that doesn't fit")
+	}
+	if !tp.TxQ.Has(orig) || !tp.TxQ.Has(othr) || tp.Length() != 2 || tp.CurSz.Load() != c.TxPMxSz {
+		t.Errorf("Failed: expected the pool to be left as it was, has %v transactions of size %v", tp.Length(), tp.CurSz.Load())
+	}
+}
+
+// TestTxPoolPersists checks that the transaction pool
+// is saved when a node is killed and restored, after
+// being revalidated, when a node starts from the file,
+// and that the fee estimator learns from the restored
+// transactions once they are mined.
+func TestTxPoolPersists(t *testing.T) {
+	f := t.TempDir() + "/txpool.json"
+	c := GenConf(GetFreePort())
+	c.MnrConf.TxPFile = f
+	genNd := pkg.New(c)
+	genNd.Start()
+
+	vld := MkGenTx(genNd, 10)
+	if !genNd.ChkTx(vld) || !genNd.Mnr.TxP.Add(vld) {
+		t.Fatal("Failed: valid transaction was not added to the pool")
+	}
+	// Spends an output that doesn't exist, so it won't
+	// be restored
+	if !genNd.Mnr.TxP.Add(MkPoolTx("a", 10)) {
+		t.Fatal("Failed: transaction was not added to the pool")
+	}
+	genNd.Kill()
+
+	c2 := GenConf(GetFreePort())
+	c2.MnrConf.TxPFile = f
+	node2 := pkg.New(c2)
+	node2.Start()
+	defer node2.Kill()
+	if node2.Mnr.TxP.Length() != 1 || !node2.Mnr.TxP.TxQ.Has(vld) {
+		t.Errorf("Failed: expected only the valid transaction to be restored, pool has %v", node2.Mnr.TxP.Length())
+	}
+	ChkTxSeenLen(t, node2, 1)
+	node2.FeeEst.HndlBlk(&block.Block{Transactions: []*tx.Transaction{vld}}, node2.Chain.Length())
+	if _, ok := node2.FeeEst.EstFeeRt(node2.FeeEst.Conf.MxTrgt); !ok {
+		t.Errorf("Failed: fee estimator did not learn from the restored transaction")
+	}
+}
+
+// TestMinRlyRt checks that a node turns away valid
+// transactions paying less than its minimum relay
+// fee rate.
+func TestMinRlyRt(t *testing.T) {
+	genNd := NewGenNd()
+	genNd.Conf.MinRlyRt = 1000
+	genNd.Start()
+	defer genNd.Kill()
+
+	low := MkGenTx(genNd, 1)
+	if _, err := genNd.ForwardTransaction(context.Background(), low.Serialize()); err == nil {
+		t.Errorf("Failed: node accepted a transaction paying %v", fee.FeeRt(low))
+	}
+	ChkTxSeenLen(t, genNd, 0)
+	hgh := MkGenTx(genNd, 1000)
+	if _, err := genNd.ForwardTransaction(context.Background(), hgh.Serialize()); err != nil {
+		t.Errorf("Failed: node turned away a transaction paying %v: %v", fee.FeeRt(hgh), err)
+	}
+	ChkTxSeenLen(t, genNd, 1)
+}
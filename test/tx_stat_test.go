@@ -0,0 +1,159 @@
+package test
+
+import (
+	"BrunoCoin/pkg"
+	"BrunoCoin/pkg/id"
+	"BrunoCoin/pkg/wallet"
+	"context"
+	"sync"
+	"testing"
+)
+
+// stats collects the statuses a subscription is
+// called with.
+type stats struct {
+	ss    []wallet.TxStat
+	mutex sync.Mutex
+}
+
+func (s *stats) add(st *wallet.TxStat) {
+	s.mutex.Lock()
+	s.ss = append(s.ss, *st)
+	s.mutex.Unlock()
+}
+
+// of returns the statuses of a transaction, in the
+// order they were seen.
+func (s *stats) of(h string) []wallet.Stts {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var ss []wallet.Stts
+	for _, st := range s.ss {
+		if st.TxHsh == h {
+			ss = append(ss, st.Stts)
+		}
+	}
+	return ss
+}
+
+// last returns the last status of a transaction.
+func (s *stats) last(h string) wallet.TxStat {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := len(s.ss) - 1; i >= 0; i-- {
+		if s.ss[i].TxHsh == h {
+			return s.ss[i]
+		}
+	}
+	return wallet.TxStat{}
+}
+
+// TestTxStat checks that a sent transaction goes from
+// pending to the pool to confirmed, that subscribers
+// are called as it does, and that it stops changing
+// once it is SafeBlkAmt deep.
+func TestTxStat(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	genNd.Start()
+	defer genNd.Kill()
+	to, _ := id.CreateSimpleID()
+	all := &stats{}
+	unsub, err := genNd.SubTx("", all.add)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsub()
+
+	h, err := genNd.SendTxCtx(context.Background(), 100, 50, to.GetPublicKeyBytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !WaitFor(func() bool { return all.last(h).Stts == wallet.InPool }) {
+		t.Fatalf("Failed: transaction was never seen in the pool, got %v", all.of(h))
+	}
+	one := &stats{}
+	unsubOne, _ := genNd.SubTx(h, one.add)
+	if ss := one.of(h); len(ss) != 1 || ss[0] != wallet.InPool {
+		t.Errorf("Failed: expected a new subscriber to get the current status, got %v", ss)
+	}
+
+	if _, err := genNd.GenerateBlocks(1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := genNd.WtTxStat(h); s.Stts != wallet.Cnfrmd || s.Confs != 1 || s.Hght != 1 || s.Fnl {
+		t.Errorf("Failed: expected the transaction to be confirmed once in block 1, got %+v", s)
+	}
+	unsubOne()
+	sfe := genNd.Conf.WtConf.SafeBlkAmt
+	if _, err := genNd.GenerateBlocks(sfe, nil); err != nil {
+		t.Fatal(err)
+	}
+	if s := all.last(h); s.Stts != wallet.Cnfrmd || s.Confs != sfe || !s.Fnl {
+		t.Errorf("Failed: expected tracking to stop at %v confirmations, got %+v", sfe, s)
+	}
+	exp := []wallet.Stts{wallet.Pndng, wallet.InPool}
+	for i := 1; i <= sfe; i++ {
+		exp = append(exp, wallet.Cnfrmd)
+	}
+	if ss := all.of(h); len(ss) != len(exp) || ss[0] != wallet.Pndng || ss[1] != wallet.InPool {
+		t.Errorf("Failed: expected statuses %v, got %v", exp, ss)
+	}
+	if ss := one.of(h); len(ss) != 2 {
+		t.Errorf("Failed: unsubscribed function was still called, got %v", ss)
+	}
+	if _, err := genNd.WtTxStat("not a hash"); err == nil {
+		t.Errorf("Failed: got a status for a transaction the wallet didn't send")
+	}
+}
+
+// TestTxStatCnflct checks that a replaced transaction
+// is dropped, and that a transaction is conflicted
+// once another transaction spending the same output
+// is mined.
+func TestTxStatCnflct(t *testing.T) {
+	c := RegtestGenConf(GetFreePort())
+	c.WtConf.RBF = true
+	genNd := pkg.New(c)
+	genNd.Start()
+	defer genNd.Kill()
+	to, _ := id.CreateSimpleID()
+	all := &stats{}
+	unsub, _ := genNd.SubTx("", all.add)
+	defer unsub()
+
+	h, err := genNd.SendTxCtx(context.Background(), 100, 50, to.GetPublicKeyBytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !WaitFor(func() bool { return all.last(h).Stts == wallet.InPool }) {
+		t.Fatalf("Failed: transaction was never seen in the pool, got %v", all.of(h))
+	}
+	bmpd, err := genNd.BumpFee(context.Background(), h, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := all.last(h); s.Stts != wallet.Drppd || !s.Fnl {
+		t.Errorf("Failed: expected the replaced transaction to be dropped for good, got %+v", s)
+	}
+	b := bmpd.Hash()
+	if !WaitFor(func() bool { return all.last(b).Stts == wallet.InPool }) {
+		t.Fatalf("Failed: replacement was never seen in the pool, got %v", all.of(b))
+	}
+
+	// Another node with the genesis key mines a transaction
+	// spending the same genesis output
+	othr := pkg.New(RegtestGenConf(GetFreePort()))
+	othr.Start()
+	defer othr.Kill()
+	othr.ConnectToPeer(genNd.Addr)
+	othr.Mnr.HndlTx(MkGenTx(othr, 1000))
+	if _, err := othr.GenerateBlocks(1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !WaitFor(func() bool { return all.last(b).Stts == wallet.Cnflctd }) {
+		t.Fatalf("Failed: expected the transaction to be conflicted, got %v", all.of(b))
+	}
+	if s := all.last(b); !s.Fnl || len(genNd.Wallet.LmnlTxs.List()) != 0 {
+		t.Errorf("Failed: expected the conflicted transaction to be given up on")
+	}
+}
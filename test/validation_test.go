@@ -1,6 +1,10 @@
 package test
 
 import (
+	"BrunoCoin/pkg/block/tx"
+	"BrunoCoin/pkg/id"
+	"BrunoCoin/pkg/proto"
+	"encoding/hex"
 	"testing"
 )
 
@@ -10,4 +14,61 @@ func TestChkTx(t *testing.T) {
 
 func TestChkBlk(t *testing.T) {
 
-}
\ No newline at end of file
+}
+
+// TestChkTxSig checks that the signatures of a
+// transaction cover all of it, so that they can't be
+// copied onto a transaction spending the same output
+// but paying someone else.
+func TestChkTxSig(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	vld := MkGenTx(genNd, 10)
+	if !genNd.ChkTx(vld) {
+		t.Fatal("Failed: signed transaction is not valid")
+	}
+	thf, _ := id.CreateSimpleID()
+	stln := vld.Serialize()
+	stln.Outputs[0].LockingScript = hex.EncodeToString(thf.GetPublicKeyBytes())
+	stln.Outputs[0].Amount -= 10
+	if genNd.ChkTx(tx.Deserialize(stln)) {
+		t.Errorf("Failed: signature was valid for a transaction paying someone else")
+	}
+	rbf := vld.Serialize()
+	rbf.Inputs[0].SequenceNumber = proto.RBFSeq
+	if genNd.ChkTx(tx.Deserialize(rbf)) {
+		t.Errorf("Failed: signature was valid for a transaction with another sequence number")
+	}
+}
+
+// TestChkTxAmts checks that a transaction can't raise
+// its fee by claiming more for an input than the
+// output it spends holds, or by spending the same
+// output twice.
+func TestChkTxAmts(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	gen := genNd.Chain.List()[0].Transactions[0]
+	o := gen.Outputs[0]
+	sgn := func(ins []*proto.TransactionInput, amt uint32) *tx.Transaction {
+		outs := []*proto.TransactionOutput{proto.NewTxOutpt(amt, o.LockingScript)}
+		nt := tx.Deserialize(proto.NewTx(0, ins, outs, 0))
+		for j := range nt.Inputs {
+			nt.Inputs[j].UnlockingScript, _ = o.MkSig(genNd.Id, nt.SigHsh(j))
+		}
+		return nt
+	}
+
+	infl := sgn([]*proto.TransactionInput{proto.NewTxInpt(gen.Hash(), 0, "", o.Amount+1000)}, o.Amount-10)
+	if genNd.ChkTx(infl) {
+		t.Errorf("Failed: accepted an input claiming more than the output it spends")
+	}
+	dbl := sgn([]*proto.TransactionInput{
+		proto.NewTxInpt(gen.Hash(), 0, "", o.Amount),
+		proto.NewTxInpt(gen.Hash(), 0, "", o.Amount),
+	}, o.Amount-10)
+	if genNd.ChkTx(dbl) {
+		t.Errorf("Failed: accepted a transaction spending the same output twice")
+	}
+	if !genNd.ChkTx(sgn([]*proto.TransactionInput{proto.NewTxInpt(gen.Hash(), 0, "", o.Amount)}, o.Amount-10)) {
+		t.Errorf("Failed: rejected a valid transaction")
+	}
+}
@@ -1,26 +1,84 @@
 package test
 
 import (
-	"BrunoCoin/pkg"
-	"BrunoCoin/pkg/block"
-	"BrunoCoin/pkg/blockchain"
-	"BrunoCoin/pkg/utils"
+	"BrunoCoin/pkg/block/tx"
+	"BrunoCoin/pkg/id"
+	"BrunoCoin/pkg/wallet"
 	"testing"
-	"time"
 )
 
-func TestAdd(t *testing.T) {
-
-}
-
+// TestChkTxs checks that liminal transactions are
+// removed once mined, and handed back to be sent
+// again once too many blocks pass without them.
 func TestChkTxs(t *testing.T) {
+	c := wallet.DefaultConfig()
+	l := wallet.NewLmnlTxs(c)
+	mnd, stl := MkPoolTx("a", 10), MkPoolTx("b", 10)
+	l.Add(mnd)
+	l.Add(stl)
+	if l.Get(mnd.Hash()) == nil || l.Get(stl.Hash()) == nil {
+		t.Fatal("Failed: liminal transactions were not added")
+	}
 
+	_, rmvd := l.ChkTxs([]*tx.Transaction{mnd})
+	if len(rmvd) != 1 || l.Get(mnd.Hash()) != nil {
+		t.Errorf("Failed: mined transaction was not removed")
+	}
+	var abv []*tx.Transaction
+	for i := uint32(1); i <= c.TxRplyThresh && len(abv) == 0; i++ {
+		abv, _ = l.ChkTxs(nil)
+	}
+	if len(abv) != 1 || abv[0].Hash() != stl.Hash() || l.Get(stl.Hash()) != nil {
+		t.Errorf("Failed: transaction was not handed back after %v blocks", c.TxRplyThresh)
+	}
 }
 
+// TestHndlTxReq checks that a request the wallet can
+// pay for is made into a liminal transaction that
+// reaches the pool, and that one it can't pay for is
+// dropped.
 func TestHndlTxReq(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	genNd.Start()
+	defer genNd.Kill()
+	to, _ := id.CreateSimpleID()
 
+	genNd.Wallet.HndlTxReq(&wallet.TxReq{PubK: to.GetPublicKeyBytes(), Amt: 10, Fee: 50})
+	ts := genNd.Wallet.LmnlTxs.List()
+	if len(ts) != 1 {
+		t.Fatalf("Failed: expected 1 liminal transaction, got %v", len(ts))
+	}
+	if !WaitFor(func() bool { return genNd.Mnr.TxP.Has(ts[0].Hash()) }) {
+		t.Errorf("Failed: transaction did not reach the pool")
+	}
+	genNd.Wallet.HndlTxReq(&wallet.TxReq{PubK: to.GetPublicKeyBytes(), Amt: 1 << 31, Fee: 50})
+	if len(genNd.Wallet.LmnlTxs.List()) != 1 {
+		t.Errorf("Failed: made a transaction for more than the balance")
+	}
 }
 
+// TestHndlBlk checks that a liminal transaction stops
+// being liminal once a block mines it, and is then
+// confirmed.
 func TestHndlBlk(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	genNd.Start()
+	defer genNd.Kill()
+	to, _ := id.CreateSimpleID()
 
-}
\ No newline at end of file
+	genNd.Wallet.HndlTxReq(&wallet.TxReq{PubK: to.GetPublicKeyBytes(), Amt: 10, Fee: 50})
+	ts := genNd.Wallet.LmnlTxs.List()
+	if len(ts) != 1 || !WaitFor(func() bool { return genNd.Mnr.TxP.Has(ts[0].Hash()) }) {
+		t.Fatal("Failed: transaction did not reach the pool")
+	}
+	if _, err := genNd.GenerateBlocks(1, nil); err != nil {
+		t.Fatal(err)
+	}
+	genNd.Wallet.HndlBlk(genNd.Chain.GetLastBlock())
+	if genNd.Wallet.LmnlTxs.Get(ts[0].Hash()) != nil {
+		t.Errorf("Failed: mined transaction stayed liminal")
+	}
+	if s, _ := genNd.WtTxStat(ts[0].Hash()); s.Stts != wallet.Cnfrmd {
+		t.Errorf("Failed: expected the mined transaction to be confirmed, got %+v", s)
+	}
+}
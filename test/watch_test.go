@@ -0,0 +1,63 @@
+package test
+
+import (
+	"BrunoCoin/pkg/id"
+	"BrunoCoin/pkg/wallet"
+	"encoding/hex"
+	"testing"
+)
+
+// TestWtchOnly checks that a watch-only wallet tracks
+// the coins of a key it imported, makes unsigned
+// transactions spending them and refuses to do
+// anything that needs keys of its own.
+func TestWtchOnly(t *testing.T) {
+	genNd := NewRegtestGenNd()
+	cold, _ := id.CreateSimpleID()
+	coldPK := cold.GetPublicKeyBytes()
+	if _, err := genNd.GenerateBlocks(8, coldPK); err != nil {
+		t.Fatal(err)
+	}
+	sbsdy := genNd.Chain.List()[1].Transactions[0].SumOutputs()
+
+	c := wallet.DefaultConfig()
+	c.WtchOnly = true
+	w := wallet.New(c, genNd.Id, genNd.Chain)
+	if w.Bal() != 0 {
+		t.Fatalf("Failed: watch-only wallet started with the node's coins")
+	}
+	if err := w.WtchScrpt("not a script"); err == nil {
+		t.Errorf("Failed: watched an invalid locking script")
+	}
+	if err := w.WtchPK(coldPK); err != nil {
+		t.Fatal(err)
+	}
+	if w.Bal() != 8*sbsdy || len(w.Hist()) != 8 {
+		t.Errorf("Failed: expected 8 coinbase outputs of the watched key, got a balance of %v", w.Bal())
+	}
+	if _, err := w.NewRcvPK(); err != wallet.ErrWtchOnly {
+		t.Errorf("Failed: watch-only wallet handed out a key")
+	}
+	if err := w.Rstr(genNd.Wallet.Bckp(), ""); err != wallet.ErrWtchOnly {
+		t.Errorf("Failed: watch-only wallet was restored from a mnemonic")
+	}
+
+	to, _ := id.CreateSimpleID()
+	req := &wallet.TxReq{PubK: to.GetPublicKeyBytes(), Amt: sbsdy + 1, Fee: 2}
+	w.HndlTxReq(req)
+	if len(w.LmnlTxs.List()) != 0 {
+		t.Errorf("Failed: watch-only wallet signed a transaction")
+	}
+	ut, err := w.MkUnsgndTx(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ut.Inputs) != 2 || ut.Fee() != 2 || len(ut.Outputs) != 2 || ut.Outputs[1].LockingScript != hex.EncodeToString(coldPK) {
+		t.Errorf("Failed: expected 2 inputs and change back to the watched key")
+	}
+	for _, i := range ut.Inputs {
+		if i.UnlockingScript != "" {
+			t.Errorf("Failed: unsigned transaction has an unlocking script")
+		}
+	}
+}